@@ -0,0 +1,249 @@
+package codeloops
+
+import (
+	"fmt"
+)
+
+// CodeLength returns the length of the code spanned by basis, which we take
+// to be the position of the highest bit set in any basis vector. Since
+// vectors are written MSB first (see constants.go), this is the number of
+// coordinates as they would be written down.
+func CodeLength(basis []uint) (n uint) {
+	var all uint
+	for _, b := range basis {
+		all |= b
+	}
+	for all != 0 {
+		n++
+		all >>= 1
+	}
+	return
+}
+
+// autSearch holds the state for a backtrack search for coordinate
+// permutations which preserve a code.
+type autSearch struct {
+	n     uint
+	basis []uint
+	code  map[uint]struct{}
+	// minimum weight codewords, used to prune the search
+	words []uint
+	img   Perm
+	used  []bool
+}
+
+func newAutSearch(basis []uint, n uint) *autSearch {
+	s := &autSearch{n: n, basis: basis, code: map[uint]struct{}{}}
+	minWt := n + 1
+	for _, v := range VectorSpace(basis) {
+		s.code[v] = struct{}{}
+		if v != 0 && BitWeight(v) < minWt {
+			minWt = BitWeight(v)
+		}
+	}
+	for v := range s.code {
+		if v != 0 && BitWeight(v) == minWt {
+			s.words = append(s.words, v)
+		}
+	}
+	return s
+}
+
+// consistent checks that the partial map img[0..d] could still extend to an
+// automorphism. Any automorphism maps the minimum weight words onto
+// themselves, so for each such word s, the image of the known part of s must
+// be exactly the known part of some (other) minimum weight word.
+func (s *autSearch) consistent(d uint) bool {
+	dom := uint(1)<<(d+1) - 1
+	var im uint
+	for i := uint(0); i <= d; i++ {
+		im |= 1 << s.img[i]
+	}
+	seen := make(map[uint]struct{}, len(s.words))
+	for _, w := range s.words {
+		seen[w&im] = struct{}{}
+	}
+	for _, w := range s.words {
+		var x uint
+		known := w & dom
+		for i := uint(0); known != 0; i++ {
+			if known&1 == 1 {
+				x |= 1 << s.img[i]
+			}
+			known >>= 1
+		}
+		if _, ok := seen[x]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *autSearch) preserves(p Perm) bool {
+	for _, b := range s.basis {
+		if _, ok := s.code[p.ApplyVec(b)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// extend assigns images for points d..n-1, returning true as soon as it has
+// built a permutation which preserves the code.
+func (s *autSearch) extend(d uint) bool {
+	if d == s.n {
+		return s.preserves(s.img)
+	}
+	for t := uint(0); t < s.n; t++ {
+		if s.used[t] {
+			continue
+		}
+		s.img[d], s.used[t] = t, true
+		if s.consistent(d) && s.extend(d+1) {
+			return true
+		}
+		s.used[t] = false
+	}
+	return false
+}
+
+// find looks for an automorphism which fixes the points 0..i-1 and maps i
+// to pt.
+func (s *autSearch) find(i, pt uint) (Perm, bool) {
+	s.img = IdentityPerm(s.n)
+	s.used = make([]bool, s.n)
+	for j := uint(0); j < i; j++ {
+		s.used[j] = true
+	}
+	if s.used[pt] {
+		return nil, false
+	}
+	s.img[i], s.used[pt] = pt, true
+	if !s.consistent(i) || !s.extend(i+1) {
+		return nil, false
+	}
+	return append(Perm{}, s.img...), true
+}
+
+// CodeAutGroup returns Aut(C), the group of permutations of the n
+// coordinates which map the code C spanned by basis onto itself.
+func CodeAutGroup(basis []uint, n uint) (g *PermGroup, e error) {
+	if CodeLength(basis) > n {
+		e = fmt.Errorf("Basis needs %d coordinates, but length is %d", CodeLength(basis), n)
+		return
+	}
+	if n > 64 {
+		e = fmt.Errorf("Can't permute %d coordinates, vectors only have 64", n)
+		return
+	}
+	if n == 0 {
+		return NewPermGroup(0, nil)
+	}
+
+	// We work up the stabilizer chain for the base 0,1,..,n-1 from the
+	// bottom. At level i, every generator found so far fixes 0..i-1, so
+	// they generate a subgroup of the stabilizer. For each point not yet in
+	// the orbit of i under that subgroup we search for an automorphism
+	// mapping i there. When we are done, the generators form a strong
+	// generating set, and Schreier-Sims just tidies up.
+	s := newAutSearch(basis, n)
+	gens := []Perm{}
+	for i := int(n) - 1; i >= 0; i-- {
+		inOrbit := orbitOf(uint(i), gens, n)
+		for pt := uint(i) + 1; pt < n; pt++ {
+			if inOrbit[pt] {
+				continue
+			}
+			p, ok := s.find(uint(i), pt)
+			if !ok {
+				continue
+			}
+			gens = append(gens, p)
+			inOrbit = orbitOf(uint(i), gens, n)
+		}
+	}
+	return NewPermGroup(n, gens)
+}
+
+func orbitOf(pt uint, gens []Perm, n uint) []bool {
+	in := make([]bool, n)
+	in[pt] = true
+	orbit := []uint{pt}
+	for i := 0; i < len(orbit); i++ {
+		for _, g := range gens {
+			if img := g[orbit[i]]; !in[img] {
+				in[img] = true
+				orbit = append(orbit, img)
+			}
+		}
+	}
+	return in
+}
+
+// AutGroup returns Aut(C) for the code underlying the loop. See
+// CodeAutGroup.
+func (cl *CL) AutGroup() (*PermGroup, error) {
+	return CodeAutGroup(cl.basis, CodeLength(cl.basis))
+}
+
+// PreservesCode reports whether the coordinate permutation p maps the code
+// underlying the loop onto itself.
+func (cl *CL) PreservesCode(p Perm) bool {
+	for _, b := range cl.basis {
+		if _, ok := cl.vm[p.ApplyVec(b)]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// LoopAut is an automorphism of a code loop which lifts a coordinate
+// permutation p preserving the code. It acts as
+//
+//	±v -> ±(-1)^s(v) p(v)
+//
+// where the sign correction s: C -> F2 satisfies
+//
+//	s(x+y) = s(x) + s(y) + theta(x,y) + theta(p(x),p(y))
+//
+// so that the theta values on each side of a product line up. This is how
+// 2^12:M24 acts on the Parker loop. Once p is fixed, s is determined by its
+// values on the basis, which may be chosen freely. It is just a Hom from the
+// loop to itself, with the permutation attached.
+type LoopAut struct {
+	*Hom
+	perm Perm
+}
+
+// LiftAutomorphism lifts the coordinate permutation p to an automorphism of
+// the loop. Bit i of basisSigns gives s(b_i) for the i'th basis vector. The
+// result is checked over all pairs of vectors before it is returned.
+func (cl *CL) LiftAutomorphism(p Perm, basisSigns uint) (la *LoopAut, e error) {
+	if p.Degree() < CodeLength(cl.basis) {
+		e = fmt.Errorf("Permutation of degree %d is too short for code of length %d", p.Degree(), CodeLength(cl.basis))
+		return
+	}
+	if !cl.PreservesCode(p) {
+		e = fmt.Errorf("Permutation %s does not preserve the code", p)
+		return
+	}
+	images := make([]CLElem, cl.basisLen)
+	for i, b := range cl.basis {
+		images[i] = CLElem{sgn: (basisSigns >> uint(i)) & 1, vec: p.ApplyVec(b)}
+	}
+	h, e := NewHom(cl, cl, images)
+	if e != nil {
+		return
+	}
+	if e = h.Verify(); e != nil {
+		e = fmt.Errorf("Lift of %s is not an automorphism: %s", p, e)
+		return
+	}
+	la = &LoopAut{Hom: h, perm: p}
+	return
+}
+
+// Perm returns the coordinate permutation underlying the automorphism.
+func (la *LoopAut) Perm() Perm {
+	return la.perm
+}
@@ -0,0 +1,286 @@
+package codeloops
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Bases can be read from text in a few common formats, so they don't have
+// to be compiled in. In every format a row of the generator matrix is read
+// most significant bit first, so the row 00001111 and the hex line 0x0f are
+// the same vector, and a basis printed with %b reads back unchanged.
+
+// BasisFormat is a text format for a basis (generator matrix).
+type BasisFormat int
+
+const (
+	// BasisAuto guesses: anything with a '[' is a matrix, lines made only
+	// of 0s and 1s (and spaces) are rows, and anything else is hex. Use a
+	// 0x prefix for hex words like 1001 which would pass for rows.
+	BasisAuto BasisFormat = iota
+	// BasisRows is one row of 0s and 1s per line. Spaces and commas
+	// between the bits are ignored.
+	BasisRows
+	// BasisHex is one hex word per line, with or without 0x.
+	BasisHex
+	// BasisMatrix is a GAP or Magma matrix, either as nested lists like
+	// [[1,0,1],[0,1,1]] (GAP entries like Z(2)^0 and 0*Z(2) are fine), one
+	// bracketed row per line as Magma prints them, or
+	// Matrix(GF(2), k, n, [ ... ]) with the entries in one flat list.
+	BasisMatrix
+)
+
+// ReadBasis reads a basis in the given format. Blank lines and anything
+// after a '#' are ignored, except in BasisMatrix where GAP and Magma
+// comments aren't worth the trouble. It is an error for the rows to have
+// different lengths, be longer than a uint, or be linearly dependent.
+func ReadBasis(r io.Reader, f BasisFormat) (basis []uint, e error) {
+	data, e := io.ReadAll(r)
+	if e != nil {
+		return
+	}
+	s := string(data)
+	if f == BasisAuto {
+		f = guessBasisFormat(s)
+	}
+	switch f {
+	case BasisRows:
+		basis, e = readBasisRows(basisLines(s))
+	case BasisHex:
+		basis, e = readBasisHex(basisLines(s))
+	case BasisMatrix:
+		basis, e = readBasisMatrix(s)
+	default:
+		e = fmt.Errorf("Unknown basis format %d", f)
+	}
+	if e != nil {
+		return nil, e
+	}
+	if len(basis) == 0 {
+		return nil, fmt.Errorf("No basis vectors found")
+	}
+	if i := dependentVector(basis); i >= 0 {
+		return nil, fmt.Errorf("Basis vector %d (0x%x) depends on the ones before it", i, basis[i])
+	}
+	return
+}
+
+// LoadBasis reads a basis from a file, guessing the format. This is what
+// the -basis flag of the commands uses.
+func LoadBasis(path string) ([]uint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBasis(f, BasisAuto)
+}
+
+// LoadBasisOr is LoadBasis, except that an empty path gives def, so a
+// command can pass its -basis flag straight through.
+func LoadBasisOr(path string, def []uint) ([]uint, error) {
+	if path == "" {
+		return def, nil
+	}
+	return LoadBasis(path)
+}
+
+// basisLines returns the non-empty lines with comments stripped.
+func basisLines(s string) (lines []string) {
+	for _, l := range strings.Split(s, "\n") {
+		if i := strings.IndexByte(l, '#'); i >= 0 {
+			l = l[:i]
+		}
+		if l = strings.TrimSpace(l); l != "" {
+			lines = append(lines, l)
+		}
+	}
+	return
+}
+
+func guessBasisFormat(s string) BasisFormat {
+	if strings.ContainsRune(s, '[') {
+		return BasisMatrix
+	}
+	for _, l := range basisLines(s) {
+		if strings.Trim(l, "01 ,\t") != "" {
+			return BasisHex
+		}
+	}
+	return BasisRows
+}
+
+// bitsToVec turns a row of 0s and 1s, first entry most significant, into a
+// vector.
+func bitsToVec(bits []string) (v uint, e error) {
+	if len(bits) > uintBits {
+		return 0, fmt.Errorf("Row of length %d is too long, the maximum is %d", len(bits), uintBits)
+	}
+	for _, b := range bits {
+		switch b {
+		case "0":
+			v <<= 1
+		case "1":
+			v = v<<1 | 1
+		default:
+			return 0, fmt.Errorf("Bad matrix entry %q", b)
+		}
+	}
+	return
+}
+
+const uintBits = 32 << (^uint(0) >> 63)
+
+func readBasisRows(lines []string) (basis []uint, e error) {
+	n := -1
+	for i, l := range lines {
+		l = strings.NewReplacer(" ", "", ",", "", "\t", "").Replace(l)
+		if n >= 0 && len(l) != n {
+			return nil, fmt.Errorf("Row %d has length %d, expected %d", i+1, len(l), n)
+		}
+		n = len(l)
+		v, err := bitsToVec(strings.Split(l, ""))
+		if err != nil {
+			return nil, fmt.Errorf("Row %d: %s", i+1, err)
+		}
+		basis = append(basis, v)
+	}
+	return
+}
+
+func readBasisHex(lines []string) (basis []uint, e error) {
+	for i, l := range lines {
+		l = strings.TrimPrefix(strings.TrimPrefix(l, "0x"), "0X")
+		v, err := strconv.ParseUint(l, 16, uintBits)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: bad hex word %q", i+1, l)
+		}
+		basis = append(basis, uint(v))
+	}
+	return
+}
+
+var (
+	gapEntries  = strings.NewReplacer("0*Z(2)", "0", "Z(2)^0", "1", "Z(2)", "1")
+	innerList   = regexp.MustCompile(`\[[^\[\]]*\]`)
+	matrixEntry = regexp.MustCompile(`[^\s,\[\]]+`)
+	magmaHeader = regexp.MustCompile(`Matrix\s*\(\s*GF\s*\(\s*2\s*\)\s*,\s*(\d+)\s*,\s*(\d+)\s*,`)
+)
+
+func readBasisMatrix(s string) (basis []uint, e error) {
+	s = gapEntries.Replace(s)
+	var rows [][]string
+	if m := magmaHeader.FindStringSubmatchIndex(s); m != nil {
+		k, _ := strconv.Atoi(s[m[2]:m[3]])
+		n, _ := strconv.Atoi(s[m[4]:m[5]])
+		list := innerList.FindString(s[m[1]:])
+		entries := matrixEntry.FindAllString(list, -1)
+		if k*n == 0 || len(entries) != k*n {
+			return nil, fmt.Errorf("Expected %d x %d entries, found %d", k, n, len(entries))
+		}
+		for i := 0; i < k; i++ {
+			rows = append(rows, entries[i*n:(i+1)*n])
+		}
+	} else {
+		for _, l := range innerList.FindAllString(s, -1) {
+			rows = append(rows, matrixEntry.FindAllString(l, -1))
+		}
+	}
+	for i, r := range rows {
+		if len(r) != len(rows[0]) {
+			return nil, fmt.Errorf("Row %d has length %d, expected %d", i+1, len(r), len(rows[0]))
+		}
+		v, err := bitsToVec(r)
+		if err != nil {
+			return nil, fmt.Errorf("Row %d: %s", i+1, err)
+		}
+		basis = append(basis, v)
+	}
+	return
+}
+
+// WriteBasis writes the basis as BasisRows of length n, or as BasisHex, so
+// that ReadBasis reads it back.
+func WriteBasis(w io.Writer, basis []uint, n uint, f BasisFormat) error {
+	bw := bufio.NewWriter(w)
+	for _, v := range basis {
+		switch f {
+		case BasisRows:
+			fmt.Fprintf(bw, "%0*b\n", n, v)
+		case BasisHex:
+			fmt.Fprintf(bw, "0x%x\n", v)
+		default:
+			return fmt.Errorf("Can't write basis format %d", f)
+		}
+	}
+	return bw.Flush()
+}
+
+// SystematicForm row reduces a basis of a code of length n to the form
+// [I | A], reading rows most significant bit first as in ReadBasis. If the
+// pivots don't fall in the first k columns, the columns are permuted to
+// put them there, which gives an equivalent code (and so an isomorphic
+// code loop). perm[c] is the original column which ended up in column c.
+func SystematicForm(basis []uint, n uint) (sys []uint, perm []uint, e error) {
+	if i := dependentVector(basis); i >= 0 {
+		return nil, nil, fmt.Errorf("Basis vector %d (0x%x) depends on the ones before it", i, basis[i])
+	}
+	if CodeLength(basis) > n {
+		return nil, nil, fmt.Errorf("Basis doesn't fit in code length %d", n)
+	}
+	rows, pivots := rowReduce(basis, n)
+	col := func(c uint) uint { return 1 << (n - 1 - c) }
+
+	// Pivot columns first, then the rest in their original order.
+	isPivot := map[uint]bool{}
+	for _, c := range pivots {
+		isPivot[c] = true
+		perm = append(perm, c)
+	}
+	for c := uint(0); c < n; c++ {
+		if !isPivot[c] {
+			perm = append(perm, c)
+		}
+	}
+	for _, v := range rows {
+		var out uint
+		for c, from := range perm {
+			if v&col(from) != 0 {
+				out |= col(uint(c))
+			}
+		}
+		sys = append(sys, out)
+	}
+	return
+}
+
+// rowReduce returns the reduced row echelon form of an independent set of
+// vectors of length n, with columns read most significant bit first, and
+// the pivot column of each row.
+func rowReduce(basis []uint, n uint) (rows []uint, pivots []uint) {
+	col := func(c uint) uint { return 1 << (n - 1 - c) }
+	rows = append([]uint{}, basis...)
+	for c, r := uint(0), 0; c < n && r < len(rows); c++ {
+		p := r
+		for p < len(rows) && rows[p]&col(c) == 0 {
+			p++
+		}
+		if p == len(rows) {
+			continue
+		}
+		rows[r], rows[p] = rows[p], rows[r]
+		for i := range rows {
+			if i != r && rows[i]&col(c) != 0 {
+				rows[i] ^= rows[r]
+			}
+		}
+		pivots = append(pivots, c)
+		r++
+	}
+	return
+}
@@ -0,0 +1,110 @@
+package codeloops
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReadBasisFormats(t *testing.T) {
+	want := []uint{0x0f, 0x3c}
+	for _, c := range []struct {
+		f  BasisFormat
+		in string
+	}{
+		{BasisRows, "00001111\n00111100\n"},
+		{BasisAuto, "# Q8\n0 0 0 0 1 1 1 1\n\n0 0 1 1 1 1 0 0 # second\n"},
+		{BasisHex, "f\n3c\n"},
+		{BasisAuto, "0x0f\n0x3c\n"},
+		{BasisAuto, "[ [ 0, 0, 0, 0, 1, 1, 1, 1 ],\n  [ 0, 0, 1, 1, 1, 1, 0, 0 ] ]\n"},
+		{BasisMatrix, "[ [ 0*Z(2), 0*Z(2), 0*Z(2), 0*Z(2), Z(2)^0, Z(2)^0, Z(2)^0, Z(2)^0 ],\n" +
+			"  [ 0*Z(2), 0*Z(2), Z(2)^0, Z(2)^0, Z(2)^0, Z(2)^0, 0*Z(2), 0*Z(2) ] ]"},
+		{BasisAuto, "[0 0 0 0 1 1 1 1]\n[0 0 1 1 1 1 0 0]\n"},
+		{BasisAuto, "G := Matrix(GF(2), 2, 8, [0,0,0,0,1,1,1,1, 0,0,1,1,1,1,0,0]);"},
+	} {
+		got, err := ReadBasis(strings.NewReader(c.in), c.f)
+		if err != nil {
+			t.Fatalf("Failed to read %q: %s", c.in, err)
+		}
+		if !equalUints(got, want) {
+			t.Fatalf("Read %q as %x, expected %x", c.in, got, want)
+		}
+	}
+
+	for _, in := range []string{
+		"0011\n011\n",       // ragged
+		"0x0f\n0x0f\n",      // dependent
+		"[[1,0,2],[0,1,1]]", // not binary
+		"Matrix(GF(2), 2, 3, [1,0,1])",
+		"",
+	} {
+		if _, err := ReadBasis(strings.NewReader(in), BasisAuto); err == nil {
+			t.Fatalf("Expected an error reading %q", in)
+		}
+	}
+}
+
+func TestWriteBasisRoundTrip(t *testing.T) {
+	for _, f := range []BasisFormat{BasisRows, BasisHex} {
+		var buf bytes.Buffer
+		if err := WriteBasis(&buf, GolayBasis, 24, f); err != nil {
+			t.Fatalf("Write failed: %s", err)
+		}
+		got, err := ReadBasis(&buf, BasisAuto)
+		if err != nil || !equalUints(got, GolayBasis) {
+			t.Fatalf("Golay basis didn't round trip in format %d: %v", f, err)
+		}
+	}
+}
+
+func TestLoadBasisOr(t *testing.T) {
+	got, err := LoadBasisOr("", HammingBasis)
+	if err != nil || !equalUints(got, HammingBasis) {
+		t.Fatalf("Empty path should give the default, got %x: %v", got, err)
+	}
+	path := filepath.Join(t.TempDir(), "q8.txt")
+	if err = os.WriteFile(path, []byte("0x0f\n0x3c\n"), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %s", path, err)
+	}
+	got, err = LoadBasisOr(path, HammingBasis)
+	if err != nil || !equalUints(got, []uint{0x0f, 0x3c}) {
+		t.Fatalf("Expected the basis from %s, got %x: %v", path, got, err)
+	}
+	if _, err = LoadBasisOr(path+".missing", HammingBasis); err == nil {
+		t.Fatalf("Expected an error for a missing file")
+	}
+}
+
+func TestSystematicForm(t *testing.T) {
+	sys, perm, err := SystematicForm(GolayBasis, 24)
+	if err != nil {
+		t.Fatalf("Failed to reduce: %s", err)
+	}
+	for i, v := range sys {
+		// The identity block is the top 12 bits.
+		if v>>12 != 1<<uint(11-i) {
+			t.Fatalf("Row %d is %024b, not systematic", i, v)
+		}
+	}
+	if len(perm) != 24 {
+		t.Fatalf("Bad permutation %v", perm)
+	}
+	// Same code up to a coordinate permutation, so still doubly even with
+	// the same weight distribution.
+	a, _ := NewCL(CLParams{Basis: GolayBasis})
+	b, err := NewCL(CLParams{Basis: sys})
+	if err != nil || b.VerifyBasis() != nil {
+		t.Fatalf("Systematic basis isn't doubly even: %v", err)
+	}
+	if !equalUints(a.WeightDistribution(), b.WeightDistribution()) {
+		t.Fatalf("Weight distributions differ")
+	}
+
+	// Pivots in the last columns force a permutation.
+	sys, perm, _ = SystematicForm([]uint{0x0f}, 8)
+	if sys[0] != 0x87 || perm[0] != 4 {
+		t.Fatalf("Expected 0x87 with column 4 first, got 0x%x, %v", sys[0], perm)
+	}
+}
@@ -0,0 +1,241 @@
+// Package bitset implements a fixed length set of bits, stored in 64 bit
+// words. Bit i is bit i%64 of word i/64, so callers that want to work on
+// whole words (eg rows of a table) can use Words directly.
+//
+// Accessors don't check bounds beyond what the slice indexing does, so
+// reading or writing past Len in the last word is not caught. The bulk
+// operations keep the unused bits of the last word clear.
+package bitset
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// Bitset is a fixed length string of bits.
+type Bitset struct {
+	n     uint
+	words []uint64
+}
+
+func nWords(n uint) uint {
+	return (n + 63) / 64
+}
+
+// New returns a Bitset of n bits, all clear.
+func New(n uint) *Bitset {
+	return &Bitset{n: n, words: make([]uint64, nWords(n))}
+}
+
+// FromWords returns a Bitset of n bits which uses words as its storage.
+func FromWords(n uint, words []uint64) (*Bitset, error) {
+	if uint(len(words)) != nWords(n) {
+		return nil, fmt.Errorf("Need %d words for %d bits, got %d", nWords(n), n, len(words))
+	}
+	b := &Bitset{n: n, words: words}
+	if n > 0 && b.words[len(b.words)-1]&^b.lastMask() != 0 {
+		return nil, fmt.Errorf("Bits set past length %d", n)
+	}
+	return b, nil
+}
+
+// Len returns the number of bits in the set.
+func (b *Bitset) Len() uint {
+	return b.n
+}
+
+// Words returns the underlying storage. Changes to the words change the set.
+func (b *Bitset) Words() []uint64 {
+	return b.words
+}
+
+// lastMask is the mask of valid bits in the last word.
+func (b *Bitset) lastMask() uint64 {
+	if b.n%64 == 0 {
+		return ^uint64(0)
+	}
+	return 1<<(b.n%64) - 1
+}
+
+// Get returns bit i as 0 or 1.
+func (b *Bitset) Get(i uint) uint {
+	return uint(b.words[i>>6]>>(i&63)) & 1
+}
+
+// Set sets bit i.
+func (b *Bitset) Set(i uint) {
+	b.words[i>>6] |= 1 << (i & 63)
+}
+
+// Clear clears bit i.
+func (b *Bitset) Clear(i uint) {
+	b.words[i>>6] &^= 1 << (i & 63)
+}
+
+// Flip inverts bit i.
+func (b *Bitset) Flip(i uint) {
+	b.words[i>>6] ^= 1 << (i & 63)
+}
+
+// SetAll sets every bit.
+func (b *Bitset) SetAll() {
+	for i := range b.words {
+		b.words[i] = ^uint64(0)
+	}
+	if len(b.words) > 0 {
+		b.words[len(b.words)-1] &= b.lastMask()
+	}
+}
+
+// ClearAll clears every bit.
+func (b *Bitset) ClearAll() {
+	for i := range b.words {
+		b.words[i] = 0
+	}
+}
+
+func (b *Bitset) check(other *Bitset) error {
+	if b.n != other.n {
+		return fmt.Errorf("Length mismatch, %d vs %d", b.n, other.n)
+	}
+	return nil
+}
+
+// Xor sets b to b XOR other.
+func (b *Bitset) Xor(other *Bitset) error {
+	if e := b.check(other); e != nil {
+		return e
+	}
+	for i, w := range other.words {
+		b.words[i] ^= w
+	}
+	return nil
+}
+
+// And sets b to b AND other.
+func (b *Bitset) And(other *Bitset) error {
+	if e := b.check(other); e != nil {
+		return e
+	}
+	for i, w := range other.words {
+		b.words[i] &= w
+	}
+	return nil
+}
+
+// Or sets b to b OR other.
+func (b *Bitset) Or(other *Bitset) error {
+	if e := b.check(other); e != nil {
+		return e
+	}
+	for i, w := range other.words {
+		b.words[i] |= w
+	}
+	return nil
+}
+
+// Count returns the number of set bits.
+func (b *Bitset) Count() (c uint) {
+	for _, w := range b.words {
+		c += uint(bits.OnesCount64(w))
+	}
+	return
+}
+
+// Range returns the n bits starting at bit start, with bit start as the
+// lowest bit of the result. n must be at most 64.
+func (b *Bitset) Range(start, n uint) (uint64, error) {
+	if n > 64 {
+		return 0, fmt.Errorf("Can't extract %d bits into one word", n)
+	}
+	if start+n > b.n {
+		return 0, fmt.Errorf("Range [%d,%d) out of bounds for length %d", start, start+n, b.n)
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	w, off := start>>6, start&63
+	x := b.words[w] >> off
+	if off+n > 64 {
+		x |= b.words[w+1] << (64 - off)
+	}
+	if n < 64 {
+		x &= 1<<n - 1
+	}
+	return x, nil
+}
+
+// Equal reports whether two sets have the same length and bits.
+func (b *Bitset) Equal(other *Bitset) bool {
+	if b.n != other.n {
+		return false
+	}
+	for i, w := range b.words {
+		if other.words[i] != w {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the bits as 0s and 1s, bit 0 first.
+func (b *Bitset) String() string {
+	var sb strings.Builder
+	sb.Grow(int(b.n))
+	for i := uint(0); i < b.n; i++ {
+		sb.WriteByte('0' + byte(b.Get(i)))
+	}
+	return sb.String()
+}
+
+// MarshalBinary encodes the set as the length followed by the words, all as
+// little endian uint64s.
+func (b *Bitset) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 8*(1+len(b.words)))
+	binary.LittleEndian.PutUint64(buf, uint64(b.n))
+	for i, w := range b.words {
+		binary.LittleEndian.PutUint64(buf[8*(i+1):], w)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a set written by MarshalBinary.
+func (b *Bitset) UnmarshalBinary(data []byte) error {
+	if len(data) < 8 || len(data)%8 != 0 {
+		return fmt.Errorf("Bad length %d for encoded bitset", len(data))
+	}
+	n := uint(binary.LittleEndian.Uint64(data))
+	words := make([]uint64, len(data)/8-1)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(data[8*(i+1):])
+	}
+	nb, e := FromWords(n, words)
+	if e != nil {
+		return e
+	}
+	*b = *nb
+	return nil
+}
+
+// MarshalText encodes the set as a string of 0s and 1s, bit 0 first.
+func (b *Bitset) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText decodes a set written by MarshalText.
+func (b *Bitset) UnmarshalText(text []byte) error {
+	nb := New(uint(len(text)))
+	for i, c := range text {
+		switch c {
+		case '0':
+		case '1':
+			nb.Set(uint(i))
+		default:
+			return fmt.Errorf("Bad character %q at %d", c, i)
+		}
+	}
+	*b = *nb
+	return nil
+}
@@ -0,0 +1,99 @@
+package bitset
+
+import (
+	"testing"
+)
+
+func TestBitsetBasic(t *testing.T) {
+	b := New(100)
+	for _, i := range []uint{0, 5, 63, 64, 99} {
+		b.Set(i)
+	}
+	if b.Count() != 5 {
+		t.Fatalf("Expected 5 bits set, got %d", b.Count())
+	}
+	if b.Get(63) != 1 || b.Get(62) != 0 {
+		t.Fatalf("Bad Get around the word boundary: %s", b)
+	}
+	b.Clear(63)
+	b.Flip(62)
+	if b.Get(63) != 0 || b.Get(62) != 1 {
+		t.Fatalf("Bad Clear or Flip: %s", b)
+	}
+	b.SetAll()
+	if b.Count() != 100 {
+		t.Fatalf("SetAll should set 100 bits, got %d", b.Count())
+	}
+	b.ClearAll()
+	if b.Count() != 0 {
+		t.Fatalf("ClearAll left %d bits set", b.Count())
+	}
+}
+
+func TestBitsetBulk(t *testing.T) {
+	a, b := New(70), New(70)
+	a.Set(1)
+	a.Set(69)
+	b.Set(69)
+	b.Set(3)
+	c := New(70)
+	c.Or(a)
+	c.And(b)
+	if c.Count() != 1 || c.Get(69) != 1 {
+		t.Fatalf("Bad And/Or: %s", c)
+	}
+	a.Xor(b)
+	if a.Count() != 2 || a.Get(1) != 1 || a.Get(3) != 1 {
+		t.Fatalf("Bad Xor: %s", a)
+	}
+	if e := a.Xor(New(71)); e == nil {
+		t.Fatalf("Xor with the wrong length should fail")
+	}
+}
+
+func TestBitsetRange(t *testing.T) {
+	b := New(200)
+	for i := uint(60); i < 70; i++ {
+		b.Set(i)
+	}
+	x, err := b.Range(58, 14)
+	if err != nil {
+		t.Fatalf("Range failed: %s", err)
+	}
+	if x != 0xffc {
+		t.Fatalf("Expected range 0xffc, got %x", x)
+	}
+	x, _ = b.Range(64, 64)
+	if x != 0x3f {
+		t.Fatalf("Expected range 0x3f, got %x", x)
+	}
+	if _, err = b.Range(190, 20); err == nil {
+		t.Fatalf("Range past the end should fail")
+	}
+}
+
+func TestBitsetMarshal(t *testing.T) {
+	b := New(130)
+	for _, i := range []uint{0, 7, 64, 129} {
+		b.Set(i)
+	}
+	data, _ := b.MarshalBinary()
+	b2 := new(Bitset)
+	if err := b2.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary failed: %s", err)
+	}
+	if !b.Equal(b2) {
+		t.Fatalf("Binary round trip failed: %s vs %s", b, b2)
+	}
+	text, _ := b.MarshalText()
+	b3 := new(Bitset)
+	if err := b3.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText failed: %s", err)
+	}
+	if !b.Equal(b3) {
+		t.Fatalf("Text round trip failed: %s vs %s", b, b3)
+	}
+	if _, err := FromWords(3, []uint64{8}); err == nil {
+		t.Fatalf("FromWords should reject bits past the length")
+	}
+}
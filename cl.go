@@ -2,9 +2,11 @@ package codeloops
 
 import (
 	"fmt"
-	"github.com/bnagy/codeloops/BitString"
+	"github.com/bnagy/codeloops/bitset"
+	"io"
 	// "log"
 	"math/rand"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -19,17 +21,28 @@ type CL struct {
 	params   CLParams
 	basisLen uint // elems in the basis
 	size     uint // elems in the loop
-	theta    *Bitstring.Bitstring
-	alpha    *Bitstring.Bitstring
-	alphaSz  uint
-	Seed     string
-	vs       []uint
-	vsV      []uint
-	vsW      []uint
-	vsAlpha  []uint
-	halfMask uint
-	vm       map[uint]uint
-	vmAlpha  map[uint]uint
+	// theta is stored as packed rows of 64 bit words, so that theta(i,j)
+	// is bit i*thetaStride*64+j. Each row starts on a fresh word, so whole
+	// rows can be XORed and counted at once.
+	theta       *bitset.Bitset
+	thetaStride uint
+	alpha       *bitset.Bitset
+	alphaSz     uint
+	Seed        string
+	vs          []uint
+	vsV         []uint
+	vsW         []uint
+	vsAlpha     []uint
+	halfMask    uint
+	vm          map[uint]uint
+	vmAlpha     map[uint]uint
+	// cocycle is set when theta is known to satisfy (S), (C) and (A), which
+	// lets us answer some questions from the basis alone.
+	cocycle bool
+	// unmap releases theta when it is backed by a file, see OpenCL.
+	unmap func() error
+	// closed is set by Close, after which theta is gone.
+	closed bool
 }
 
 type CLParams struct {
@@ -45,25 +58,42 @@ func NewCL(p CLParams) (cl *CL, e error) {
 	cl.basis = p.Basis
 	cl.basisLen = uint(len(p.Basis))
 	cl.size = 1 << cl.basisLen
-	cl.vs = VectorSpace(p.Basis)
-	m := make(map[uint]uint)
-	for i, v := range cl.vs {
-		m[v] = uint(i)
-	}
-	cl.vm = m
+	cl.initSpace()
 
-	cl.theta = Bitstring.NewBitstring(int(cl.size * cl.size))
+	cl.theta = bitset.New(cl.size * cl.thetaStride * 64)
 	e = cl.buildTheta(p.Random, p.Seed)
 	if e != nil {
 		return
 	}
+	// The Griess construction always gives a code cocycle for a doubly even
+	// code. Loops made any other way have to earn the flag by passing
+	// VerifyCodeCocycle.
+	cl.cocycle = cl.VerifyBasis() == nil
+	cl.initAlpha()
+	e = cl.buildAlpha()
+	return
+}
+
+// initSpace sets up the vector space and the theta layout from the basis.
+func (cl *CL) initSpace() {
+	cl.vs = VectorSpace(cl.basis)
+	m := make(map[uint]uint)
+	for i, v := range cl.vs {
+		m[v] = uint(i)
+	}
+	cl.vm = m
+	cl.thetaStride = (cl.size + 63) / 64
+}
 
+// initAlpha sets up the split of the basis into V and W, and allocates the
+// alpha square.
+func (cl *CL) initAlpha() {
 	// No idea what will happen for odd-length bases
-	cl.vsV = VectorSpace(p.Basis[:cl.basisLen/2])
-	cl.vsW = VectorSpace(p.Basis[cl.basisLen/2:])
+	cl.vsV = VectorSpace(cl.basis[:cl.basisLen/2])
+	cl.vsW = VectorSpace(cl.basis[cl.basisLen/2:])
 	// HACK this is bigger than it needs to be, because we have a second copy
 	// of the zero vector in vsW. The trouble is that all the calculations for
-	// setting indicies in the Bitstring work much better if we keep all of
+	// setting indicies in the bitset work much better if we keep all of
 	// the shifts as integers, ie we have the sides of the alpha square stay a
 	// power of two.
 	cl.vsAlpha = append(cl.vsV, cl.vsW...)
@@ -78,10 +108,7 @@ func NewCL(p CLParams) (cl *CL, e error) {
 	mask, _ := strconv.ParseUint(strings.Repeat("1", int(cl.basisLen/2)), 2, 0)
 	cl.halfMask = uint(mask)
 	cl.alphaSz = uint(len(cl.vsV) + len(cl.vsW))
-	cl.alpha = Bitstring.NewBitstring(int(cl.alphaSz * cl.alphaSz))
-	e = cl.buildAlpha()
-
-	return
+	cl.alpha = bitset.New(cl.alphaSz * cl.alphaSz)
 }
 
 // NewElemFromIdx creates a signed entry in the loop represented by CL.
@@ -174,6 +201,11 @@ func (cl *CL) LoopElems() (cles []CLElem) {
 	return
 }
 
+// Basis returns the basis the loop was built from.
+func (cl *CL) Basis() []uint {
+	return cl.basis
+}
+
 // VectorSpace returns a slice of the underlying vectors. For the code loops
 // we are working with, these are just the unsigned loop elements.
 func (cl *CL) VectorSpace() (vecs []uint) {
@@ -207,34 +239,57 @@ func (cl *CL) Decompose(vec uint) (v, w uint, e error) {
 
 // PrintBasis displays the loop basis.
 func (cl *CL) PrintBasis() {
-	fmt.Print("----------\n")
+	cl.FprintBasis(os.Stdout)
+}
+
+// FprintBasis writes the loop basis to w, in the same format as PrintBasis.
+func (cl *CL) FprintBasis(w io.Writer) {
+	fmt.Fprint(w, "----------\n")
 	for i, vec := range cl.basis {
-		fmt.Printf("%.2d: %.8b (%d) \n", i, vec, vec)
+		fmt.Fprintf(w, "%.2d: %.8b (%d) \n", i, vec, vec)
 	}
-	fmt.Print("----------\n")
+	fmt.Fprint(w, "----------\n")
 }
 
 // PrintLoopElems displays the loop elements.
 func (cl *CL) PrintLoopElems() {
-	fmt.Print("----------\n")
+	cl.FprintLoopElems(os.Stdout)
+}
+
+// FprintLoopElems writes the loop elements to w.
+func (cl *CL) FprintLoopElems(w io.Writer) {
+	fmt.Fprint(w, "----------\n")
 	for i, cle := range cl.LoopElems() {
-		fmt.Printf("%.2d: %.8b (%s) \n", i, cle.vec, cle.String())
+		fmt.Fprintf(w, "%.2d: %.8b (%s) \n", i, cle.vec, cle.String())
 	}
-	fmt.Print("----------\n")
+	fmt.Fprint(w, "----------\n")
 }
 
 // PrintVectorSpace displays the underlying vector space.
 func (cl *CL) PrintVectorSpace() {
-	fmt.Print("----------\n")
+	cl.FprintVectorSpace(os.Stdout)
+}
+
+// FprintVectorSpace writes the underlying vector space to w.
+func (cl *CL) FprintVectorSpace(w io.Writer) {
+	fmt.Fprint(w, "----------\n")
 	for i, vec := range cl.VectorSpace() {
-		fmt.Printf("%.2d: %.8b (%x) \n", i, vec, vec)
+		fmt.Fprintf(w, "%.2d: %.8b (%x) \n", i, vec, vec)
 	}
-	fmt.Print("----------\n")
+	fmt.Fprint(w, "----------\n")
 }
 
 // VerifyBasis checks the supplied basis to ensure that it is a doubly even binary
-// code.
+// code. It only looks at the basis vectors and their pairwise intersections
+// (see CheckDoublyEven), and on failure the error is a *DivisibilityError
+// naming a word of bad weight and the basis vectors which sum to it.
 func (cl *CL) VerifyBasis() (e error) {
+	return CheckDoublyEven(cl.basis)
+}
+
+// verifyBasis is the brute force version of VerifyBasis, which looks at
+// every word.
+func (cl *CL) verifyBasis() (e error) {
 	for _, vec := range cl.VectorSpace() {
 		if BitWeight(vec)%4 != 0 {
 			e = fmt.Errorf("Bad vector %b, bitweight not a multiple of 4.", vec)
@@ -284,31 +339,31 @@ func (cl *CL) verifyMoufang2() (e error) {
 
 	// This version is an order of magnitude faster, and uses an identity from [Gri86] p. 226
 	// Moufang iff t(x,y) + t (z,x) + t(x+y, z+x) = t(y,z) + t(x, y+z) + t(x+y+z, x)
+	// The triples are checked in parallel, but the witness is always the
+	// first failing triple in the order of the VectorSpace.
 
 	vs := cl.VectorSpace()
-	var x, y, z uint
-	for i := 0; i < len(vs); i++ {
-		for j := 0; j < len(vs); j++ {
-			for k := 0; k < len(vs); k++ {
-				x, y, z = vs[i], vs[j], vs[k]
-				if (cl.thetaByVecFast(x, y)^
-					cl.thetaByVecFast(z, x)^
-					cl.thetaByVecFast(x^y, z^x))^
-					cl.thetaByVecFast(y, z)^
-					cl.thetaByVecFast(x, y^z)^
-					cl.thetaByVecFast(x^y^z, x) != 0 {
-					return fmt.Errorf("Code loop failed Moufang identity at %x, %x, %x", x, y, z)
-
-				}
-			}
-		}
+	i, j, k, found := firstTriple(len(vs), len(vs), len(vs), func(i, j, k int) bool {
+		x, y, z := vs[i], vs[j], vs[k]
+		return (cl.thetaByVecFast(x, y)^
+			cl.thetaByVecFast(z, x)^
+			cl.thetaByVecFast(x^y, z^x))^
+			cl.thetaByVecFast(y, z)^
+			cl.thetaByVecFast(x, y^z)^
+			cl.thetaByVecFast(x^y^z, x) != 0
+	})
+	if found {
+		return fmt.Errorf("Code loop failed Moufang identity at %x, %x, %x", vs[i], vs[j], vs[k])
 	}
 	return nil
 }
 
 // IsMoufang checks whether the loop is Moufang.
 func (cl *CL) IsMoufang() bool {
-	e := cl.verifyMoufang2()
+	if cl.theta == nil {
+		return cl.verifyMoufang2() == nil
+	}
+	e := cl.verifyMoufangRows()
 	return e == nil
 }
 
@@ -343,26 +398,34 @@ func (cl *CL) verifyAssoc2() bool {
 	// Faster check, using the cocycle identity
 	// (this only runs on the vector space elems; half the size)
 
-	vs := cl.VectorSpace()
-	var x, y, z uint
-	for i := 0; i < len(vs); i++ {
-		for j := 0; j < len(vs); j++ {
-			for k := 0; k < len(vs); k++ {
-				x, y, z = vs[i], vs[j], vs[k]
-				if (cl.thetaByVecFast(x, y^z) ^ cl.thetaByVecFast(y, z) ^
-					cl.thetaByVecFast(x^y, z) ^ cl.thetaByVecFast(x, y)) != 0 {
-					return false
+	_, _, _, found := cl.assocWitness()
+	return !found
+}
 
-				}
-			}
-		}
+// assocWitness returns the first triple of vectors (in the order of the
+// VectorSpace) for which the cocycle identity fails, checking in parallel.
+func (cl *CL) assocWitness() (x, y, z uint, found bool) {
+	vs := cl.VectorSpace()
+	i, j, k, found := firstTriple(len(vs), len(vs), len(vs), func(i, j, k int) bool {
+		x, y, z := vs[i], vs[j], vs[k]
+		return (cl.thetaByVecFast(x, y^z) ^ cl.thetaByVecFast(y, z) ^
+			cl.thetaByVecFast(x^y, z) ^ cl.thetaByVecFast(x, y)) != 0
+	})
+	if found {
+		x, y, z = vs[i], vs[j], vs[k]
 	}
-	return true
+	return
 }
 
 // IsAssoc checks whether the loop is fully associative (ie a group).
 func (cl *CL) IsAssoc() (isAssoc bool) {
-	return cl.verifyAssoc2()
+	if cl.cocycle {
+		return cl.verifyAssoc3()
+	}
+	if cl.theta == nil {
+		return cl.verifyAssoc2()
+	}
+	return cl.verifyAssocRows()
 }
 
 func (cl *CL) setThetaByVec(v1, v2, val uint) error {
@@ -378,7 +441,7 @@ func (cl *CL) setThetaByVec(v1, v2, val uint) error {
 		return fmt.Errorf("Args to setThetaByVec (%x, %x) overflow bitstring of len %d", v1, v2, cl.size*cl.size)
 	}
 	if val > 0 {
-		cl.theta.SetBit(int(i1<<cl.basisLen | i2))
+		cl.setThetaBit(i1, i2)
 	}
 	return nil
 }
@@ -393,7 +456,7 @@ func (cl *CL) ThetaByVec(v1, v2 uint) (uint, error) {
 	if !ok {
 		return 0, fmt.Errorf("Vector %x not in vector space", v2)
 	}
-	return uint(cl.theta.GetBit(int(i1<<cl.basisLen | i2))), nil
+	return cl.thetaBit(i1, i2), nil
 }
 
 func (cl *CL) ThetaAlphaByVec(x, y uint) (uint, error) {
@@ -449,8 +512,27 @@ func (cl *CL) thetaAlphaByVecFast(x, y uint) uint {
 	return (a + b + c + d + e + f + g + h + i) % 2
 }
 
+// thetaAlphaByIdx is thetaAlphaByVecFast for vectors given by index. The low
+// half of the index bits pick out the V part and the high half the W part,
+// so there is no need to Decompose.
+func (cl *CL) thetaAlphaByIdx(i1, i2 uint) uint {
+	h := cl.basisLen / 2
+	v1, w1 := cl.vsV[i1&cl.halfMask], cl.vsW[i1>>h]
+	v2, w2 := cl.vsV[i2&cl.halfMask], cl.vsW[i2>>h]
+	a := cl.alphaByVecFast(v1, v2)
+	b := cl.alphaByVecFast(w1, w2)
+	c := cl.alphaByVecFast(v1, w1)
+	d := cl.alphaByVecFast(w2, v2)
+	e := cl.alphaByVecFast(v1^v2, w1^w2)
+	f := BitWeight(v2&(w1^w2)) / 2
+	g := BitWeight(v1 & v2 & (w1 ^ w2))
+	hh := BitWeight((w1 & w2 & v2))
+	i := BitWeight(v1 & w1 & (v2 ^ w2))
+	return (a + b + c + d + e + f + g + hh + i) % 2
+}
+
 func (cl *CL) thetaByVecFast(v1, v2 uint) uint {
-	return uint(cl.theta.GetBit(int(cl.vm[v1]<<cl.basisLen | cl.vm[v2])))
+	return cl.thetaBit(cl.vm[v1], cl.vm[v2])
 }
 
 func (cl *CL) setThetaByIdx(i1, i2, val uint) error {
@@ -458,7 +540,7 @@ func (cl *CL) setThetaByIdx(i1, i2, val uint) error {
 		return fmt.Errorf("Args to setThetaByIdx (%x, %x) overflow bitstring of len %d", i1, i2, cl.size*cl.size)
 	}
 	if val > 0 {
-		cl.theta.SetBit(int(i1<<cl.basisLen | i2))
+		cl.setThetaBit(i1, i2)
 	}
 	return nil
 }
@@ -468,11 +550,27 @@ func (cl *CL) ThetaByIdx(i1, i2 uint) (uint, error) {
 	if i1 >= 1<<cl.basisLen || i2 >= 1<<cl.basisLen {
 		return 0, fmt.Errorf("Args to ThetaByIdx (%x, %x) overflow bitstring of len %d", i1, i2, cl.size*cl.size)
 	}
-	return uint(cl.theta.GetBit(int(i1<<cl.basisLen | i2))), nil
+	return cl.thetaBit(i1, i2), nil
 }
 
 func (cl *CL) thetaByIdxFast(i1, i2 uint) uint {
-	return uint(cl.theta.GetBit(int(i1<<cl.basisLen | i2)))
+	return cl.thetaBit(i1, i2)
+}
+
+func (cl *CL) thetaBit(i1, i2 uint) uint {
+	if cl.theta == nil {
+		if cl.closed {
+			// The alpha formula is only right for partitioned bases, so
+			// don't quietly fall back to it.
+			panic("codeloops: theta used after Close")
+		}
+		return cl.thetaAlphaByIdx(i1, i2)
+	}
+	return cl.theta.Get(i1*cl.thetaStride<<6 | i2)
+}
+
+func (cl *CL) setThetaBit(i1, i2 uint) {
+	cl.theta.Set(i1*cl.thetaStride<<6 | i2)
 }
 
 func (cl *CL) buildTheta(random bool, seed int64) error {
@@ -603,7 +701,7 @@ func (cl *CL) setAlphaByVec(v1, v2, val uint) error {
 	// to optimise, we could check this right at the start, but I want the
 	// error checking to run.
 	if val > 0 {
-		cl.alpha.SetBit(int((i1 * cl.alphaSz) | i2))
+		cl.alpha.Set(i1*cl.alphaSz | i2)
 	}
 	return nil
 }
@@ -636,11 +734,11 @@ func (cl *CL) AlphaByVec(v1, v2 uint) (uint, error) {
 	if !ok {
 		return 0, fmt.Errorf("Vector %x not in alpha space", v2)
 	}
-	return uint(cl.alpha.GetBit(int(i1*cl.alphaSz | i2))), nil
+	return cl.alpha.Get(i1*cl.alphaSz | i2), nil
 }
 
 func (cl *CL) alphaByVecFast(v1, v2 uint) uint {
-	return uint(cl.alpha.GetBit(int(cl.vmAlpha[v1]*cl.alphaSz | cl.vmAlpha[v2])))
+	return cl.alpha.Get(cl.vmAlpha[v1]*cl.alphaSz | cl.vmAlpha[v2])
 }
 
 // AlphaByIdx returns alpha(i1, i2) where i1 and i2 are indicies into the Alpha square.
@@ -648,7 +746,7 @@ func (cl *CL) AlphaByIdx(i1, i2 uint) (uint, error) {
 	if i1 >= cl.alphaSz || i2 >= cl.alphaSz {
 		return 0, fmt.Errorf("Args to AlphaByIdx (%x, %x) overflow bitstring of len %d", i1, i2, cl.alphaSz*cl.alphaSz)
 	}
-	return uint(cl.alpha.GetBit(int(i1*cl.alphaSz | i2))), nil
+	return cl.alpha.Get(i1*cl.alphaSz | i2), nil
 }
 
 // This is legacy code which is here in case I ever need to regenerate some
@@ -1,21 +1,26 @@
 package main
 
 import (
+	"flag"
 	"github.com/bnagy/codeloops"
-	"github.com/fogleman/gg"
+	"github.com/bnagy/codeloops/render"
 	"log"
+	"os"
 )
 
 const PIXEL = 2    // This should be even!
 const TRUNCATE = 0 // at this many pixels
 
-func SpecialVectorSpace(b6, b5, b1 []uint) []uint {
-	// We assume that b6 and b5 generate subspaces with only 0 in common, and
-	// that b1 is not in vs6 or vs5.
+// maxDim is the largest basis we draw: the image has a pixel square for
+// every pair of the 2^k vectors.
+const maxDim = 12
 
+// SpecialVectorSpace orders the span of bV and bW as V, then v+w for each v
+// in V and each non-zero w in W, where V and W are the spans of bV and bW.
+// Together bV and bW must be independent.
+func SpecialVectorSpace(bV, bW []uint) []uint {
 	final := []uint{}
-	vsV := codeloops.VectorSpace(b6)
-	bW := append(b5, b1...)
+	vsV := codeloops.VectorSpace(bV)
 	vsW := codeloops.VectorSpace(bW)
 	final = append(final, vsV...)
 
@@ -24,80 +29,52 @@ func SpecialVectorSpace(b6, b5, b1 []uint) []uint {
 			final = append(final, v^w)
 		}
 	}
-
-	if len(final) != 1<<12 {
-		log.Fatalf("Wrong number of vectors constructing special vector space ordering: %d", len(final))
-	}
 	return final
 }
 
 func main() {
+	basisFile := flag.String("basis", "", "read the basis from this file (0/1 rows, hex words, or a GAP/Magma matrix)")
+	flag.Parse()
 
-	B := codeloops.GolayAwesumBasis
+	B, err := codeloops.LoadBasisOr(*basisFile, codeloops.GolayAwesumBasis)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = codeloops.CheckDoublyEven(B); err != nil {
+		log.Fatalf("Basis is not doubly even, so it has no code loop: %s", err)
+	}
+	if len(B) > maxDim {
+		log.Fatalf("Basis has dimension %d, but loop_pics only draws bases of dimension up to %d", len(B), maxDim)
+	}
 
-	var pxSquare uint
-	var imgInner int
+	pxSquare := 1 << uint(len(B))
 	if TRUNCATE > 0 {
-		imgInner = (TRUNCATE * PIXEL)
 		pxSquare = TRUNCATE
-	} else {
-		imgInner = (1 << uint(len(B)) * PIXEL)
-		pxSquare = 1 << uint(len(B))
 	}
-
-	// I am from the 'moar variables moar better' school.
-	imgInnerf := float64(imgInner)
 	borderW := PIXEL / 2
 	if borderW == 0 {
 		borderW = PIXEL
 	}
-	borderWf := float64(borderW)
-	borderLenf := imgInnerf + 2*borderWf
-	imgW := imgInner + 4*int(borderW) // black border, white border * 2 sides
-	imgH := imgInner + 4*int(borderW)
-
-	dc := gg.NewContext(imgW, imgH)
-	dc.SetRGB(1, 1, 1)
-	dc.Clear()
 
-	cl, _ := codeloops.NewCL(codeloops.CLParams{Basis: B})
-	// This will only work for bases that have the special partitioning
-	// property (see cmd/partition). Otherwise, use the line below.
-	vs := SpecialVectorSpace(B[:6], B[6:11], B[11:12])
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: B})
+	if err != nil {
+		log.Fatal(err)
+	}
+	// The V/W ordering shows the most structure for bases with the special
+	// partitioning property (see cmd/partition), but works for any basis.
+	// For the plain ordering use the line below.
+	h := len(B) / 2
+	vs := SpecialVectorSpace(B[:h], B[h:])
 	// vs := codeloops.VectorSpace(B)
 
-	dc.SetRGB(0, 0, 0)
-
-	// top border
-	dc.DrawRectangle(borderWf, borderWf, borderLenf, borderWf)
-	dc.Fill()
-	// left...
-	dc.DrawRectangle(borderWf, borderWf, borderWf, borderLenf)
-	dc.Fill()
-	// bottom...
-	dc.DrawRectangle(borderWf, borderWf*2+imgInnerf, borderLenf, borderWf)
-	dc.Fill()
-	// right.
-	dc.DrawRectangle(borderWf*2+imgInnerf, borderWf, borderWf, borderLenf)
-	dc.Fill()
-
-	// draw a black rectangle for all theta(x,y) == 1
-	for i := 0; i < int(pxSquare); i++ {
-		for j := 0; j < int(pxSquare); j++ {
-			res, e := cl.ThetaByVec(vs[i], vs[j])
-			if e != nil {
-				log.Fatal(e)
-			}
-			if res > 0 {
-				dc.DrawRectangle(
-					2*borderWf+float64(j*PIXEL), // x
-					2*borderWf+float64(i*PIXEL), // y
-					float64(PIXEL),              // w
-					float64(PIXEL))              // h
-				dc.Fill()
-			}
-		}
+	// draw a black square for all theta(x,y) == 1, in the special ordering
+	f, e := os.Create("out.png")
+	if e != nil {
+		log.Fatal(e)
+	}
+	defer f.Close()
+	opts := render.Options{Pixel: PIXEL, Border: borderW}
+	if e = render.Write(f, render.Theta(cl, vs[:pxSquare]), opts, render.PNG); e != nil {
+		log.Fatal(e)
 	}
-
-	dc.SavePNG("out.png")
 }
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"flag"
+	"github.com/bnagy/codeloops"
+	"github.com/bnagy/codeloops/render"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// The twisted cocycle for M16(C2 x C4) from Figure 2 of the paper. It isn't
+// the theta our construction produces for any basis, so it's just copied
+// out here.
+var m16 = [][]uint{
+	{0, 0, 0, 0, 0, 0, 0, 0},
+	{0, 0, 0, 0, 1, 1, 1, 1},
+	{0, 0, 0, 0, 1, 1, 1, 1},
+	{0, 0, 0, 0, 1, 1, 1, 1},
+	{0, 0, 0, 0, 0, 0, 0, 0},
+	{0, 0, 1, 1, 1, 1, 0, 0},
+	{0, 1, 0, 1, 1, 0, 1, 0},
+	{0, 1, 1, 0, 1, 0, 0, 1},
+}
+
+type figure struct {
+	name   string
+	m      render.Matrix
+	labels []string
+	opts   render.Options
+}
+
+func main() {
+	format := flag.String("format", "eps", "output format: eps, pdf, svg, png, pgm or pbm")
+	labels := flag.Bool("labels", false, "label the rows and columns (vector formats only)")
+	dir := flag.String("dir", ".", "directory to write the figures to")
+	basisFile := flag.String("basis", "", "read the basis for alpha_awesum from this file (0/1 rows, hex words, or a GAP/Magma matrix)")
+	flag.Parse()
+
+	f, err := render.FormatFromPath("x." + *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Q8 is the code loop of two weight 4 words meeting in 2 places, and
+	// its theta is exactly Figure 1.
+	q8, err := codeloops.NewCL(codeloops.CLParams{Basis: []uint{0x0f, 0x3c}})
+	if err != nil {
+		log.Fatal(err)
+	}
+	m, _ := render.Rows(m16)
+
+	// Theta for Parker's loop restricted to V∪W, as in cmd/alpha_pics.
+	B, err := codeloops.LoadBasisOr(*basisFile, codeloops.GolayAwesumBasis)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = codeloops.CheckDoublyEven(B); err != nil {
+		log.Fatalf("Basis is not doubly even, so it has no code loop: %s", err)
+	}
+	parker, err := codeloops.NewCL(codeloops.CLParams{Basis: B})
+	if err != nil {
+		log.Fatal(err)
+	}
+	h := len(B) / 2
+	alpha := append(codeloops.VectorSpace(B[:h]), codeloops.VectorSpace(B[h:])...)
+	alphaLabels := append(render.VectorLabels(h), render.VectorLabels(len(B)-h)...)
+
+	for _, fig := range []figure{
+		{"quaternion_cocyc", render.Theta(q8, nil), render.VectorLabels(2), render.Options{Pixel: 40, Border: 2}},
+		{"m16", m, render.VectorLabels(3), render.Options{Pixel: 40, Border: 2}},
+		{"alpha_awesum", render.Theta(parker, alpha), alphaLabels, render.Options{Pixel: 4, Border: 2, FontSize: 3}},
+	} {
+		if *labels {
+			fig.opts.Labels = fig.labels
+		}
+		out, err := os.Create(filepath.Join(*dir, fig.name+"."+*format))
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err = render.Write(out, fig.m, fig.opts, f); err != nil {
+			log.Fatal(err)
+		}
+		if err = out.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
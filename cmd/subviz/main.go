@@ -1,9 +1,12 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"github.com/bnagy/codeloops"
+	"github.com/bnagy/codeloops/render"
 	"github.com/fogleman/gg"
+	"image/color"
 	"log"
 	"math"
 )
@@ -20,9 +23,26 @@ const MAX_LABEL_H = 50
 // Do we want to label the elements in the multiplication table?
 const CELL_LABELS = false
 
+var (
+	red    = color.RGBA{0xae, 0x20, 0x13, 0xff}
+	green  = color.RGBA{0x61, 0xa6, 0x2f, 0xff}
+	yellow = color.RGBA{0xf8, 0xcc, 0x74, 0xff}
+	blue   = color.RGBA{0x3b, 0x57, 0xe2, 0xff}
+	rotten = color.RGBA{0x8d, 0xae, 0x4b, 0xff}
+	puke   = color.RGBA{0x6f, 0x82, 0x4a, 0xff}
+)
+
 func main() {
+	basisFile := flag.String("basis", "", "read the basis from this file (0/1 rows, hex words, or a GAP/Magma matrix)")
+	flag.Parse()
 
-	basis := codeloops.GolayBasis
+	basis, err := codeloops.LoadBasisOr(*basisFile, codeloops.GolayBasis)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = codeloops.CheckDoublyEven(basis); err != nil {
+		log.Fatalf("Basis is not doubly even, so it has no code loop: %s", err)
+	}
 	choose := 5
 
 	subspaces := 0
@@ -40,13 +60,11 @@ func main() {
 	pxSquare := (1 << uint(choose)) // * 2 for full loop
 
 	// I am from the 'moar variables moar better' school.
-	imgInnerf := float64(imgInner)
 	borderW := PIXEL / 8
 	if borderW == 0 {
 		borderW = PIXEL
 	}
 	borderWf := float64(borderW)
-	borderLenf := imgInnerf + 2*borderWf
 	imgW := imgInner + 4*int(borderW) // black border, white border * 2 sides
 	imgWf := float64(imgW)
 	labelHf := math.Ceil(imgWf / 6) // arbitrarily pleasing ratio
@@ -65,7 +83,7 @@ func main() {
 	dc := gg.NewContext(panelW*xreps, panelH*yreps)
 	dc.SetRGB(1, 1, 1)
 	dc.Clear()
-	err := dc.LoadFontFace("/Library/Fonts/Envy Code R.ttf", labelHf*3/8)
+	err = dc.LoadFontFace("/Library/Fonts/Envy Code R.ttf", labelHf*3/8)
 	if err != nil {
 		log.Fatalf("Unable to load font Envy Code R: %s", err)
 	}
@@ -78,25 +96,15 @@ func main() {
 		tlYf := float64(count/yreps) * panelHf
 		count++
 
-		// top border
-		dc.DrawRectangle(tlXf+borderWf, tlYf+labelHf+borderWf, borderLenf, borderWf)
-		dc.Fill()
-		// left...
-		dc.DrawRectangle(tlXf+borderWf, tlYf+labelHf+borderWf, borderWf, borderLenf)
-		dc.Fill()
-		// bottom...
-		dc.DrawRectangle(tlXf+borderWf, tlYf+labelHf+borderWf*2+imgInnerf, borderLenf, borderWf)
-		dc.Fill()
-		// right.
-		dc.DrawRectangle(tlXf+borderWf*2+imgInnerf, tlYf+labelHf+borderWf, borderWf, borderLenf)
-		dc.Fill()
-
 		// draw the label
 		thisBasis := []uint{}
 		for _, idx := range s {
-			thisBasis = append(thisBasis, codeloops.GolayBasis[idx])
+			thisBasis = append(thisBasis, basis[idx])
+		}
+		cl, err := codeloops.NewCL(codeloops.CLParams{Basis: thisBasis})
+		if err != nil {
+			log.Fatal(err)
 		}
-		cl, _ := codeloops.NewCL(codeloops.CLParams{Basis: thisBasis})
 
 		if labelH > 0 {
 			label := "Basis:"
@@ -109,41 +117,32 @@ func main() {
 			dc.DrawStringAnchored(fmt.Sprintf("Seed: %s", cl.Seed), tlXf+borderWf, tlYf+(labelHf+borderWf)*3/4, 0, 0.5)
 		}
 
-		res := new(codeloops.CLElem)
-		vm := cl.VectorIdxMap()
-		loopElems := cl.LoopElems()
-		negColor, posColor := "", ""
+		// negative products are One, and get the first colour
+		var pal render.Palette
 		if cl.IsAssoc() {
-			negColor = "61a62f" // red and green
-			posColor = "ae2013"
+			pal = render.Palette{Zero: green, One: red} // red and green
 		} else if cl.IsMoufang() {
-			negColor = "3b57e2" // blue and yellow
-			posColor = "f8cc74"
+			pal = render.Palette{Zero: blue, One: yellow} // blue and yellow
 		} else {
 			// if this happens, it is either a bug or a mathematical
 			// discovery.
-			negColor = "6F824A" // puke green and rotten green
-			posColor = "8dae4b"
+			pal = render.Palette{Zero: puke, One: rotten} // puke green and rotten green
 		}
-		for i := 0; i < int(pxSquare); i++ {
-			for j := 0; j < int(pxSquare); j++ {
-				_, e := cl.Mul(&loopElems[i], &loopElems[j], res)
-				if e != nil {
-					log.Fatal(e)
-				}
-				if res.Sign() > 0 {
-					dc.SetHexColor(posColor)
-				} else {
-					dc.SetHexColor(negColor)
-				}
-				dc.DrawRectangle(
-					tlXf+2*borderWf+float64(j*PIXEL),         // x
-					tlYf+labelHf+2*borderWf+float64(i*PIXEL), // y
-					float64(PIXEL),                           // w
-					float64(PIXEL))                           // h
-				dc.Fill()
-				if CELL_LABELS {
-					dc.SetRGB(0, 0, 0)
+		pal.Background, pal.Frame = color.White, color.Black
+		opts := render.Options{Pixel: PIXEL, Border: borderW, Palette: &pal}
+		dc.DrawImage(render.Image(render.Signs(cl, pxSquare), opts), int(tlXf), int(tlYf)+labelH)
+
+		if CELL_LABELS {
+			res := new(codeloops.CLElem)
+			vm := cl.VectorIdxMap()
+			loopElems := cl.LoopElems()
+			dc.SetRGB(0, 0, 0)
+			for i := 0; i < int(pxSquare); i++ {
+				for j := 0; j < int(pxSquare); j++ {
+					_, e := cl.Mul(&loopElems[i], &loopElems[j], res)
+					if e != nil {
+						log.Fatal(e)
+					}
 					dc.DrawStringAnchored(
 						fmt.Sprintf("%x", vm[res.Vec()]),
 						tlXf+2*borderWf+float64(j*PIXEL)+PIXEL/2,
@@ -1,7 +1,7 @@
 package codeloops
 
 import (
-	// "fmt"
+	"fmt"
 	"testing"
 )
 
@@ -292,6 +292,49 @@ func TestHammingTheta(t *testing.T) {
 	}
 }
 
+func TestVerifyReportHamming(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		cl, err := NewCL(CLParams{Basis: HammingBasis, Random: true, Seed: seed})
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		r := cl.VerifyReport()
+		if !r.CodeCocycle() {
+			t.Fatalf("Hamming theta is not a code cocycle:\n%s", r)
+		}
+		if !r.Moufang.Pass {
+			t.Fatalf("Hamming loop not Moufang:\n%s", r)
+		}
+		if r.Assoc.Pass {
+			t.Fatalf("Hamming loop is associative:\n%s", r)
+		}
+	}
+}
+
+func TestVerifyReportBadHamming(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: badHammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	r := cl.VerifyReport()
+	if r.CodeCocycle() || r.Moufang.Pass {
+		t.Fatalf("Bad Hamming basis passed:\n%s", r)
+	}
+	if !r.Normalization.Pass {
+		t.Fatalf("Griess theta should always be normalized:\n%s", r)
+	}
+	// The witness must be the first violation, which is also what the
+	// early exit check finds.
+	err = cl.verifyMoufang2()
+	w := r.Moufang.Witness
+	if len(w) != 3 || err.Error() != fmt.Sprintf("Code loop failed Moufang identity at %x, %x, %x", w[0], w[1], w[2]) {
+		t.Fatalf("Report witness %x doesn't match %q", w, err)
+	}
+	if r.Moufang.Violations < 1 {
+		t.Fatalf("Expected Moufang violations to be counted.")
+	}
+}
+
 func TestDecompose(t *testing.T) {
 	cl, err := NewCL(CLParams{Basis: GolaySplitBasis})
 	if err != nil {
@@ -526,3 +569,51 @@ func BenchmarkLoopElemsGolay(b *testing.B) {
 		cl.LoopElems()
 	}
 }
+
+func TestParallelWitness(t *testing.T) {
+	// The parallel checks must report the same witness as a serial scan.
+	cl, err := NewCL(CLParams{Basis: badGolayBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	vs := cl.VectorSpace()
+	var serial error
+serial:
+	for _, x := range vs {
+		for _, y := range vs {
+			for _, z := range vs {
+				if (cl.thetaByVecFast(x, y)^
+					cl.thetaByVecFast(z, x)^
+					cl.thetaByVecFast(x^y, z^x))^
+					cl.thetaByVecFast(y, z)^
+					cl.thetaByVecFast(x, y^z)^
+					cl.thetaByVecFast(x^y^z, x) != 0 {
+					serial = fmt.Errorf("Code loop failed Moufang identity at %x, %x, %x", x, y, z)
+					break serial
+				}
+			}
+		}
+	}
+	if serial == nil {
+		t.Fatalf("Bad Golay basis passed serial Moufang test.")
+	}
+	for n := 0; n < 10; n++ {
+		err = cl.verifyMoufang2()
+		if err == nil || err.Error() != serial.Error() {
+			t.Fatalf("Parallel witness %q doesn't match serial %q", err, serial)
+		}
+	}
+
+	cl, err = NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	x, y, z, found := cl.assocWitness()
+	if !found {
+		t.Fatalf("Hamming loop has no associativity witness.")
+	}
+	// (0, b1, b2) is associative, the first failure should be at x = b1
+	if x != cl.VectorSpace()[1] {
+		t.Fatalf("Expected first witness to start at %x, got %x, %x, %x", cl.VectorSpace()[1], x, y, z)
+	}
+}
@@ -0,0 +1,313 @@
+package codeloops
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/bnagy/codeloops/bitset"
+)
+
+// LoopDescription is a JSON friendly description of a code loop, for tools
+// that don't want to link against Go. The schema is in
+// schema/loop_description.schema.json. Vectors are hex strings like
+// "0x1805a3", since JSON numbers lose precision past 2^53.
+type LoopDescription struct {
+	Basis      []string              `json:"basis"`
+	Length     uint                  `json:"length"`
+	Dimension  uint                  `json:"dimension"`
+	Partition  *PartitionDescription `json:"partition,omitempty"`
+	Random     bool                  `json:"random"`
+	Seed       int64                 `json:"seed"`
+	Theta      *ThetaDescription     `json:"theta,omitempty"`
+	Invariants *Invariants           `json:"invariants,omitempty"`
+}
+
+// PartitionDescription gives the split of the basis into V and W used for
+// the alpha square.
+type PartitionDescription struct {
+	V []string `json:"v"`
+	W []string `json:"w"`
+}
+
+// ThetaDescription holds the complete cocycle. theta is treated as a
+// boolean function of 2k variables, t = i<<k | j for the vectors with
+// indices i and j, so variables 0..k-1 are the index bits of the second
+// argument and k..2k-1 those of the first.
+//
+// With Encoding "base64", Data holds the truth table, bit t being bit t%8
+// of byte t/8. With Encoding "anf", Monomials lists the terms of the
+// algebraic normal form, each as a hex mask of its variables. For code
+// cocycles the ANF is usually much smaller.
+type ThetaDescription struct {
+	Encoding  string   `json:"encoding"`
+	Data      string   `json:"data,omitempty"`
+	Monomials []string `json:"monomials,omitempty"`
+}
+
+// Invariants are properties of the loop which can be computed from it, and
+// are included for convenience. They are ignored when building a loop.
+type Invariants struct {
+	Associative        bool   `json:"associative"`
+	Commutative        bool   `json:"commutative"`
+	Moufang            bool   `json:"moufang"`
+	CenterSize         int    `json:"center_size"`
+	NucleusSize        int    `json:"nucleus_size"`
+	WeightDistribution []uint `json:"weight_distribution"`
+}
+
+// Theta encodings for Description.
+const (
+	ThetaNone   = ""
+	ThetaBase64 = "base64"
+	ThetaANF    = "anf"
+)
+
+// HexStrings formats vectors as hex strings like "0x1805a3", as in a
+// LoopDescription.
+func HexStrings(vs []uint) []string {
+	ss := make([]string, len(vs))
+	for i, v := range vs {
+		ss[i] = fmt.Sprintf("0x%x", v)
+	}
+	return ss
+}
+
+func parseHexStrings(ss []string) (vs []uint, e error) {
+	for _, s := range ss {
+		v, err := strconv.ParseUint(s, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Bad vector %q: %s", s, err)
+		}
+		vs = append(vs, uint(v))
+	}
+	return
+}
+
+// Description describes the loop. thetaEncoding is one of ThetaNone,
+// ThetaBase64 or ThetaANF. If invariants is set the invariants are
+// computed too, which needs IsMoufang and so is slow for big loops.
+func (cl *CL) Description(thetaEncoding string, invariants bool) (d *LoopDescription, e error) {
+	d = &LoopDescription{
+		Basis:     HexStrings(cl.basis),
+		Length:    CodeLength(cl.basis),
+		Dimension: cl.basisLen,
+		Partition: &PartitionDescription{
+			V: HexStrings(cl.basis[:cl.basisLen/2]),
+			W: HexStrings(cl.basis[cl.basisLen/2:]),
+		},
+		Random: cl.params.Random,
+		Seed:   cl.params.Seed,
+	}
+	switch thetaEncoding {
+	case ThetaNone:
+	case ThetaBase64:
+		words := cl.truthTable().Words()
+		buf := make([]byte, 8*len(words))
+		for i, w := range words {
+			binary.LittleEndian.PutUint64(buf[8*i:], w)
+		}
+		buf = buf[:(cl.size*cl.size+7)/8]
+		d.Theta = &ThetaDescription{Encoding: ThetaBase64, Data: base64.StdEncoding.EncodeToString(buf)}
+	case ThetaANF:
+		tt := cl.truthTable()
+		mobius(tt.Words(), 2*cl.basisLen)
+		d.Theta = &ThetaDescription{Encoding: ThetaANF, Monomials: []string{}}
+		for t := uint(0); t < tt.Len(); t++ {
+			if tt.Get(t) != 0 {
+				d.Theta.Monomials = append(d.Theta.Monomials, fmt.Sprintf("0x%x", t))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("Unknown theta encoding %q", thetaEncoding)
+	}
+	if invariants {
+		d.Invariants = &Invariants{
+			Associative:        cl.IsAssoc(),
+			Commutative:        cl.IsCommutative(),
+			Moufang:            cl.IsMoufang(),
+			CenterSize:         len(cl.Center()),
+			NucleusSize:        len(cl.Nucleus()),
+			WeightDistribution: cl.WeightDistribution(),
+		}
+	}
+	return
+}
+
+// MarshalJSON describes the loop with its theta table in base64 and no
+// invariants.
+func (cl *CL) MarshalJSON() ([]byte, error) {
+	d, e := cl.Description(ThetaBase64, false)
+	if e != nil {
+		return nil, e
+	}
+	return json.Marshal(d)
+}
+
+// UnmarshalJSON builds the loop from a LoopDescription.
+func (cl *CL) UnmarshalJSON(data []byte) error {
+	d := new(LoopDescription)
+	if e := json.Unmarshal(data, d); e != nil {
+		return e
+	}
+	built, e := d.NewCL()
+	if e != nil {
+		return e
+	}
+	*cl = *built
+	return nil
+}
+
+// NewCL builds the loop described. Without a theta table the basis and
+// parameters are passed to NewCL. With one, the loop is built straight from
+// the table instead (so loops built with a time based seed still round
+// trip). A table which isn't a code cocycle is still accepted, so broken
+// loops can be described and studied, but VerifyCodeCocycle decides whether
+// the basis shortcuts in IsAssoc and friends can be trusted.
+func (d *LoopDescription) NewCL() (cl *CL, e error) {
+	basis, e := parseHexStrings(d.Basis)
+	if e != nil {
+		return
+	}
+	if len(basis) == 0 || uint(len(basis)) != d.Dimension {
+		return nil, fmt.Errorf("Dimension %d doesn't match basis of length %d", d.Dimension, len(basis))
+	}
+	if d.Length != 0 && d.Length < CodeLength(basis) {
+		return nil, fmt.Errorf("Basis doesn't fit in code length %d", d.Length)
+	}
+	if d.Partition != nil {
+		v, err := parseHexStrings(d.Partition.V)
+		if err != nil {
+			return nil, err
+		}
+		w, err := parseHexStrings(d.Partition.W)
+		if err != nil {
+			return nil, err
+		}
+		h := len(basis) / 2
+		if !equalUints(v, basis[:h]) || !equalUints(w, basis[h:]) {
+			return nil, fmt.Errorf("Partition must split the basis into halves")
+		}
+	}
+	if d.Theta == nil {
+		return NewCL(CLParams{Basis: basis, Random: d.Random, Seed: d.Seed})
+	}
+	if i := dependentVector(basis); i >= 0 {
+		return nil, fmt.Errorf("Basis vector %d (0x%x) depends on the ones before it", i, basis[i])
+	}
+
+	tt, e := d.Theta.truthTable(uint(len(basis)))
+	if e != nil {
+		return nil, e
+	}
+	cl = new(CL)
+	cl.basis = basis
+	cl.basisLen = uint(len(basis))
+	cl.size = 1 << cl.basisLen
+	cl.params = CLParams{Basis: basis, Random: d.Random, Seed: d.Seed}
+	cl.Seed = fmt.Sprintf("0x%x", d.Seed)
+	cl.initSpace()
+	cl.theta = bitset.New(cl.size * cl.thetaStride * 64)
+	for i := uint(0); i < cl.size; i++ {
+		for j := uint(0); j < cl.size; j++ {
+			if tt.Get(i<<cl.basisLen|j) != 0 {
+				cl.setThetaBit(i, j)
+			}
+		}
+	}
+	cl.initAlpha()
+	if e = cl.buildAlpha(); e != nil {
+		return nil, e
+	}
+	cl.cocycle = cl.VerifyCodeCocycle() == nil
+	return
+}
+
+func equalUints(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// truthTable returns theta as a bitset, bit i<<k | j being theta(i, j).
+func (cl *CL) truthTable() *bitset.Bitset {
+	tt := bitset.New(cl.size * cl.size)
+	for i := uint(0); i < cl.size; i++ {
+		if cl.theta != nil && cl.size >= 64 {
+			copy(tt.Words()[i*cl.thetaStride:], cl.thetaRow(i))
+			continue
+		}
+		for j := uint(0); j < cl.size; j++ {
+			if cl.thetaByIdxFast(i, j) != 0 {
+				tt.Set(i<<cl.basisLen | j)
+			}
+		}
+	}
+	return tt
+}
+
+// truthTable decodes the theta table for a loop of dimension k.
+func (td *ThetaDescription) truthTable(k uint) (*bitset.Bitset, error) {
+	n := uint(1) << (2 * k)
+	tt := bitset.New(n)
+	switch td.Encoding {
+	case ThetaBase64:
+		buf, e := base64.StdEncoding.DecodeString(td.Data)
+		if e != nil {
+			return nil, fmt.Errorf("Bad theta data: %s", e)
+		}
+		if uint(len(buf)) != (n+7)/8 {
+			return nil, fmt.Errorf("Need %d bytes of theta data, got %d", (n+7)/8, len(buf))
+		}
+		for t := uint(0); t < n; t++ {
+			if buf[t/8]>>(t%8)&1 != 0 {
+				tt.Set(t)
+			}
+		}
+	case ThetaANF:
+		ms, e := parseHexStrings(td.Monomials)
+		if e != nil {
+			return nil, e
+		}
+		for _, m := range ms {
+			if m >= n {
+				return nil, fmt.Errorf("Monomial 0x%x has too many variables", m)
+			}
+			tt.Flip(m)
+		}
+		mobius(tt.Words(), 2*k)
+	default:
+		return nil, fmt.Errorf("Unknown theta encoding %q", td.Encoding)
+	}
+	return tt, nil
+}
+
+// mobius applies the Möbius transform to a truth table of a boolean
+// function of nvars variables, in place. This converts between the truth
+// table and the algebraic normal form, in either direction.
+func mobius(words []uint64, nvars uint) {
+	for v := uint(0); v < nvars; v++ {
+		if v < 6 {
+			// f[t] ^= f[t ^ 1<<v] for t with bit v set, inside each word
+			s := uint(1) << v
+			for w := range words {
+				words[w] ^= (words[w] & xorSwap[v]) << s
+			}
+			continue
+		}
+		ws := 1 << (v - 6)
+		for w := range words {
+			if w&ws != 0 {
+				words[w] ^= words[w^ws]
+			}
+		}
+	}
+}
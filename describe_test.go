@@ -0,0 +1,94 @@
+package codeloops
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDescribeRoundTrip(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis, Random: true})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	for _, enc := range []string{ThetaBase64, ThetaANF} {
+		d, err := cl.Description(enc, false)
+		if err != nil {
+			t.Fatalf("Failed to describe loop: %s", err)
+		}
+		data, err := json.Marshal(d)
+		if err != nil {
+			t.Fatalf("Failed to marshal: %s", err)
+		}
+		cl2 := new(CL)
+		if err = json.Unmarshal(data, cl2); err != nil {
+			t.Fatalf("Failed to unmarshal %s: %s", enc, err)
+		}
+		if !cl.EqualTheta(cl2) || !cl.alpha.Equal(cl2.alpha) {
+			t.Fatalf("Theta differs after %s round trip", enc)
+		}
+	}
+
+	// A broken theta is accepted, but isn't trusted.
+	flipTheta(cl, 5, 9)
+	d, _ := cl.Description(ThetaBase64, false)
+	bad, err := d.NewCL()
+	if err != nil {
+		t.Fatalf("Failed to build loop with a broken theta: %s", err)
+	}
+	if !cl.EqualTheta(bad) || bad.cocycle {
+		t.Fatalf("Broken theta should round trip without being marked as a code cocycle")
+	}
+}
+
+func TestDescribeGolayANF(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: GolayBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	d, err := cl.Description(ThetaANF, false)
+	if err != nil {
+		t.Fatalf("Failed to describe loop: %s", err)
+	}
+	cl2, err := d.NewCL()
+	if err != nil {
+		t.Fatalf("Failed to build loop: %s", err)
+	}
+	if !cl.EqualTheta(cl2) || !cl2.cocycle {
+		t.Fatalf("Theta differs after ANF round trip")
+	}
+}
+
+func TestDescribeInvariants(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	d, _ := cl.Description(ThetaNone, true)
+	inv := d.Invariants
+	if inv.Associative || inv.Commutative || !inv.Moufang {
+		t.Fatalf("Hamming loop should be Moufang but not associative: %+v", inv)
+	}
+	wd := []uint{1, 0, 0, 0, 14, 0, 0, 0, 1}
+	if !equalUints(inv.WeightDistribution, wd) {
+		t.Fatalf("Expected weight distribution %v, got %v", wd, inv.WeightDistribution)
+	}
+	// Ω is in the code, and every word has even overlap with it
+	if inv.CenterSize != 4 || inv.NucleusSize != 4 {
+		t.Fatalf("Expected center and nucleus ±0, ±Ω, got %d and %d", inv.CenterSize, inv.NucleusSize)
+	}
+	// The brute force versions must agree with the basis ones.
+	cl.cocycle = false
+	if len(cl.Center()) != 4 || len(cl.Nucleus()) != 4 {
+		t.Fatalf("Brute force center and nucleus disagree")
+	}
+
+	// The center of the Parker loop is ±0, ±Ω
+	cl, err = NewCL(CLParams{Basis: GolayBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	center := cl.Center()
+	if len(center) != 4 || center[1].vec != 0xffffff {
+		t.Fatalf("Expected the Parker loop center to be ±0, ±Ω, got %v", center)
+	}
+}
@@ -0,0 +1,205 @@
+package codeloops
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// A weight distribution wd, as returned by WeightDistribution, is the
+// weight enumerator W(x,y) = sum wd[i] x^(n-i) y^i of a code of length
+// n = len(wd)-1. The functions here work on those, so they can be used for
+// any code, not just ones that fit a LinearCode.
+
+// EnumeratorString formats the weight enumerator as a polynomial in x and
+// y, eg x^8 + 14x^4y^4 + y^8 for e8.
+func EnumeratorString(wd []uint) string {
+	n := len(wd) - 1
+	power := func(v string, e int) string {
+		switch e {
+		case 0:
+			return ""
+		case 1:
+			return v
+		}
+		return fmt.Sprintf("%s^%d", v, e)
+	}
+	terms := []string{}
+	for i, a := range wd {
+		if a == 0 {
+			continue
+		}
+		mono := power("x", n-i) + power("y", i)
+		coef := fmt.Sprint(a)
+		if a == 1 && mono != "" {
+			coef = ""
+		}
+		terms = append(terms, coef+mono)
+	}
+	if len(terms) == 0 {
+		return "0"
+	}
+	return strings.Join(terms, " + ")
+}
+
+// krawtchouk returns K_j(i) = sum_s (-1)^s C(i,s) C(n-i,j-s), the
+// coefficient of x^(n-j) y^j in (x+y)^(n-i) (x-y)^i.
+func krawtchouk(n, i, j int) *big.Int {
+	k, t := new(big.Int), new(big.Int)
+	for s := 0; s <= j && s <= i; s++ {
+		if j-s > n-i {
+			continue
+		}
+		t.Binomial(int64(i), int64(s))
+		t.Mul(t, new(big.Int).Binomial(int64(n-i), int64(j-s)))
+		if s%2 == 1 {
+			k.Sub(k, t)
+		} else {
+			k.Add(k, t)
+		}
+	}
+	return k
+}
+
+// MacWilliams returns the weight distribution of the dual of a linear code
+// with weight distribution wd, using the MacWilliams identity
+// W_dual(x,y) = W(x+y, x-y) / |C|. It's an error if the result isn't a
+// list of non-negative integers, which means wd didn't come from a linear
+// code.
+func MacWilliams(wd []uint) ([]uint, error) {
+	n := len(wd) - 1
+	size := new(big.Int)
+	for _, a := range wd {
+		size.Add(size, new(big.Int).SetUint64(uint64(a)))
+	}
+	if size.Sign() == 0 {
+		return nil, fmt.Errorf("Empty weight distribution")
+	}
+	dual := make([]uint, n+1)
+	for j := range dual {
+		b := new(big.Int)
+		for i, a := range wd {
+			if a != 0 {
+				b.Add(b, new(big.Int).Mul(new(big.Int).SetUint64(uint64(a)), krawtchouk(n, i, j)))
+			}
+		}
+		q, r := new(big.Int).QuoRem(b, size, new(big.Int))
+		if r.Sign() != 0 || q.Sign() < 0 || !q.IsUint64() {
+			return nil, fmt.Errorf("Weight distribution isn't from a linear code: dual count %s/%s for weight %d", b, size, j)
+		}
+		dual[j] = uint(q.Uint64())
+	}
+	return dual, nil
+}
+
+// DualWeightDistribution returns the weight distribution of the dual code
+// by the MacWilliams identity, which only needs the 2^k words of the code.
+func (c *LinearCode) DualWeightDistribution() ([]uint, error) {
+	return MacWilliams(c.WeightDistribution())
+}
+
+// CheckMacWilliams enumerates the dual code and checks its weight
+// distribution against the MacWilliams identity. Enumerating the dual
+// takes 2^(n-k) steps, so this is only for small duals.
+func (c *LinearCode) CheckMacWilliams() error {
+	mw, err := c.DualWeightDistribution()
+	if err != nil {
+		return err
+	}
+	var enum []uint
+	if d, err := c.Dual(); err == nil {
+		enum = d.WeightDistribution()
+	} else {
+		// the dual of the whole space is just 0
+		enum = make([]uint, c.n+1)
+		enum[0] = 1
+	}
+	if !equalUints(mw, enum) {
+		return fmt.Errorf("MacWilliams gives %v for the dual, enumeration gives %v", mw, enum)
+	}
+	return nil
+}
+
+// poly is a homogeneous polynomial in x and y, p[i] being the coefficient
+// of x^(deg-i) y^i.
+type poly []*big.Int
+
+func newPoly(coefs ...int64) poly {
+	p := make(poly, len(coefs))
+	for i, c := range coefs {
+		p[i] = big.NewInt(c)
+	}
+	return p
+}
+
+func (p poly) mul(q poly) poly {
+	r := make(poly, len(p)+len(q)-1)
+	for i := range r {
+		r[i] = new(big.Int)
+	}
+	for i, a := range p {
+		for j, b := range q {
+			r[i+j].Add(r[i+j], new(big.Int).Mul(a, b))
+		}
+	}
+	return r
+}
+
+func (p poly) pow(e int) poly {
+	r := newPoly(1)
+	for ; e > 0; e-- {
+		r = r.mul(p)
+	}
+	return r
+}
+
+var (
+	// x^8 + 14x^4y^4 + y^8, the weight enumerator of e8
+	gleasonPhi8 = newPoly(1, 0, 0, 0, 14, 0, 0, 0, 1)
+	// x^4y^4(x^4 - y^4)^4, the part of the Golay enumerator that isn't
+	// a power of phi8
+	gleasonXi24 = newPoly(0, 0, 0, 0, 1, 0, 0, 0, 0).mul(newPoly(1, 0, 0, 0, -1).pow(4))
+)
+
+// Gleason checks that wd is the weight distribution of a doubly even self
+// dual code, as far as Gleason's theorem can tell. Such codes have length
+// divisible by 8, and their enumerators are polynomials in
+// phi8 = x^8 + 14x^4y^4 + y^8 and xi24 = x^4y^4(x^4 - y^4)^4, so
+//
+//	W = sum_{i <= n/24} a_i phi8^(n/8 - 3i) xi24^i.
+//
+// Each term starts at y^(4i) with coefficient 1, so the a_i can be read off
+// wd[0], wd[4], wd[8], ... in turn, and then all of wd has to match. The a_i
+// are returned. Codes which are doubly even but not self dual only have the
+// weaker property that every weight is divisible by 4, which
+// IsDoublyEven checks directly.
+func Gleason(wd []uint) ([]*big.Int, error) {
+	n := len(wd) - 1
+	if n <= 0 || n%8 != 0 {
+		return nil, fmt.Errorf("Length %d isn't divisible by 8", n)
+	}
+	for w, a := range wd {
+		if a != 0 && w%4 != 0 {
+			return nil, fmt.Errorf("Weight %d isn't divisible by 4", w)
+		}
+	}
+	rest := make(poly, n+1)
+	for i, a := range wd {
+		rest[i] = new(big.Int).SetUint64(uint64(a))
+	}
+	coefs := []*big.Int{}
+	for i := 0; i <= n/24; i++ {
+		term := gleasonPhi8.pow(n/8 - 3*i).mul(gleasonXi24.pow(i))
+		a := new(big.Int).Set(rest[4*i])
+		coefs = append(coefs, a)
+		for j := range rest {
+			rest[j].Sub(rest[j], new(big.Int).Mul(a, term[j]))
+		}
+	}
+	for j, r := range rest {
+		if r.Sign() != 0 {
+			return nil, fmt.Errorf("Enumerator isn't a polynomial in phi8 and xi24, the y^%d terms are off by %s", j, r)
+		}
+	}
+	return coefs, nil
+}
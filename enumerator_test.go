@@ -0,0 +1,59 @@
+package codeloops
+
+import (
+	"testing"
+)
+
+func TestEnumeratorString(t *testing.T) {
+	if s := EnumeratorString(E8().WeightDistribution()); s != "x^8 + 14x^4y^4 + y^8" {
+		t.Fatalf("Bad e8 enumerator %s", s)
+	}
+}
+
+func TestMacWilliams(t *testing.T) {
+	hamming7, _ := NewLinearCode([]uint{0x46, 0x25, 0x13, 0x0f}, 7)
+	rm25, _ := ReedMuller(2, 5)
+	d10, _ := D(10)
+	for _, c := range []*LinearCode{hamming7, rm25, d10, E8()} {
+		if err := c.CheckMacWilliams(); err != nil {
+			t.Fatalf("MacWilliams failed for a [%d,%d] code: %s", c.Length(), c.Dimension(), err)
+		}
+	}
+	// The dual of RM(r,m) is RM(m-r-1,m)
+	rm15, _ := ReedMuller(1, 5)
+	rm35, _ := ReedMuller(3, 5)
+	dwd, err := rm15.DualWeightDistribution()
+	if err != nil || !equalUints(dwd, rm35.WeightDistribution()) {
+		t.Fatalf("Dual of RM(1,5) should look like RM(3,5): %v", err)
+	}
+	if _, err = MacWilliams([]uint{1, 1, 1}); err == nil {
+		t.Fatalf("1 + y + y^2 isn't the enumerator of a linear code")
+	}
+}
+
+func TestGleason(t *testing.T) {
+	golay, _ := NewLinearCode(GolayBasis, 24)
+	qr48, _ := ExtendedQR(47)
+	d16, _ := DPlus(16)
+	for _, c := range []*LinearCode{E8(), d16, golay, qr48} {
+		coefs, err := Gleason(c.WeightDistribution())
+		if err != nil {
+			t.Fatalf("Gleason failed for length %d: %s", c.Length(), err)
+		}
+		if len(coefs) != int(c.Length()/24)+1 || coefs[0].Int64() != 1 {
+			t.Fatalf("Bad Gleason coefficients %v", coefs)
+		}
+	}
+	// Golay has no weight 4 words, so it's phi8^3 - 42 xi24
+	coefs, _ := Gleason(golay.WeightDistribution())
+	if coefs[1].Int64() != -42 {
+		t.Fatalf("Expected Golay = phi8^3 - 42 xi24, got %v", coefs)
+	}
+	if _, err := Gleason(E8().WeightDistribution()[:5]); err == nil {
+		t.Fatalf("Length 4 should fail")
+	}
+	rm15, _ := ReedMuller(1, 5)
+	if _, err := Gleason(rm15.WeightDistribution()); err == nil {
+		t.Fatalf("RM(1,5) isn't self dual, so Gleason should fail")
+	}
+}
@@ -0,0 +1,280 @@
+// Package export writes code loops in formats other tools can read.
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/bits"
+	"strings"
+
+	"github.com/bnagy/codeloops"
+)
+
+// GAPOptions controls the GAP export.
+type GAPOptions struct {
+	// CayleyLimit is the largest loop (in elements) which is written out as
+	// a Cayley table. Bigger loops are built inside GAP from the cocycle of
+	// the basis. Zero means DefaultCayleyLimit.
+	CayleyLimit int
+}
+
+// DefaultCayleyLimit is the CayleyLimit used when none is given.
+const DefaultCayleyLimit = 256
+
+// Elements are numbered as in LoopElems, from 1: the positive elements in
+// the order of the VectorSpace, then the negative ones. The identity +0 is
+// element 1, as LOOPS wants.
+
+// GAP writes a GAP script which builds the loop with the LOOPS package as
+// L, and then checks IsMoufangLoop, IsAssociative, IsCommutative, Center and
+// Nucleus against the values computed here, printing a line for each.
+//
+// Small loops are written as a Cayley table for LoopByCayleyTable. For big
+// loops the table would be enormous, so instead the script carries the
+// cubic cocycle of the basis, which is a few k x k matrices, and a sign for
+// each vector, and GAP builds the loop from the right multiplications (see
+// cocycle). That works for any doubly even basis. The formula is checked
+// against theta for every pair before anything is written, so a loop whose
+// theta isn't a code cocycle is an error.
+func GAP(w io.Writer, cl *codeloops.CL, opts GAPOptions) (e error) {
+	limit := opts.CayleyLimit
+	if limit == 0 {
+		limit = DefaultCayleyLimit
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Code loop of order %d, basis %s\n", 2*cl.Size(), hexList(cl.Basis()))
+	fmt.Fprintf(bw, "LoadPackage(\"loops\");\n\n")
+
+	if 2*cl.Size() <= limit {
+		writeCayleyTable(bw, cl)
+	} else {
+		var c *cocycle
+		if c, e = newCocycle(cl); e != nil {
+			return
+		}
+		c.write(bw)
+	}
+	writeChecks(bw, cl)
+	return bw.Flush()
+}
+
+func hexList(vs []uint) string {
+	return "[" + strings.Join(codeloops.HexStrings(vs), ", ") + "]"
+}
+
+func intList(xs []int) string {
+	ss := make([]string, len(xs))
+	for i, x := range xs {
+		ss[i] = fmt.Sprint(x)
+	}
+	return "[" + strings.Join(ss, ",") + "]"
+}
+
+// elemNumber returns the 1-based GAP number of an element.
+func elemNumber(cl *codeloops.CL, x *codeloops.CLElem) int {
+	n := int(cl.VectorIdxMap()[x.Vec()]) + 1
+	if x.Sign() == codeloops.Neg {
+		n += cl.Size()
+	}
+	return n
+}
+
+func writeCayleyTable(w io.Writer, cl *codeloops.CL) {
+	elems := cl.LoopElems()
+	res := new(codeloops.CLElem)
+	fmt.Fprintf(w, "ct := [\n")
+	for i := range elems {
+		row := make([]int, len(elems))
+		for j := range elems {
+			cl.Mul(&elems[i], &elems[j], res)
+			row[j] = elemNumber(cl, res)
+		}
+		sep := ","
+		if i == len(elems)-1 {
+			sep = ""
+		}
+		fmt.Fprintf(w, "  %s%s\n", intList(row), sep)
+	}
+	fmt.Fprintf(w, "];\nL := LoopByCayleyTable(ct);\n\n")
+}
+
+func gapBool(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+func writeChecks(w io.Writer, cl *codeloops.CL) {
+	center, nucleus := []int{}, []int{}
+	for _, x := range cl.Center() {
+		center = append(center, elemNumber(cl, &x))
+	}
+	for _, x := range cl.Nucleus() {
+		nucleus = append(nucleus, elemNumber(cl, &x))
+	}
+	fmt.Fprintf(w, `CheckEq := function(name, got, want)
+  if got = want then
+    Print(name, " ok\n");
+  else
+    Print(name, " FAILED: got ", got, ", expected ", want, "\n");
+  fi;
+end;
+
+Idx := x -> Position(Elements(L), x);
+CheckEq("IsMoufangLoop", IsMoufangLoop(L), %s);
+CheckEq("IsAssociative", IsAssociative(L), %s);
+CheckEq("IsCommutative", IsCommutative(L), %s);
+CheckEq("Center", Set(List(Center(L), Idx)), Set(%s));
+CheckEq("Nucleus", Set(List(Nucleus(L), Idx)), Set(%s));
+`, gapBool(cl.IsMoufang()), gapBool(cl.IsAssoc()), gapBool(cl.IsCommutative()),
+		intList(center), intList(nucleus))
+}
+
+// cocycle holds what GAP needs to compute theta for any doubly even basis.
+// Writing x and y as coordinate vectors over the basis,
+//
+//	theta0(x, y) = sum p_i x_i y_i + sum_(i<j) c_ij x_i y_j
+//	             + sum_(i<j<l) a_ijl (x_i x_j y_l + x_i x_l y_j + x_j x_l y_i)
+//
+// where p, c and a are the squares, commutators and associators of the
+// basis vectors, is a code cocycle. Any other code cocycle for the basis,
+// eg one with random choices, is theta0 changed by the signs s of the
+// elements, so
+//
+//	theta(x, y) = theta0(x, y) + s(x) + s(y) + s(x+y)
+//
+// theta0 is bilinear in y, so for each x it is the dot product of y with
+// T(x) = xB + (x U_l x)_l, which GAP computes with GF(2) matrices.
+type cocycle struct {
+	k int
+	B [][]int   // B[i][j] = p_i if i = j, c_ij if i < j
+	U [][][]int // U[l][i][j] = a_ijl if i < j and neither is l
+	S []int     // s by vector index
+	t []uint    // T(x) by vector index, as a mask over the basis
+}
+
+func parity(x uint) int {
+	return bits.OnesCount(x) % 2
+}
+
+func newCocycle(cl *codeloops.CL) (*cocycle, error) {
+	basis := cl.Basis()
+	k := len(basis)
+	c := &cocycle{k: k, B: make([][]int, k), U: make([][][]int, k)}
+	for i, bi := range basis {
+		c.B[i] = make([]int, k)
+		c.B[i][i] = int(codeloops.BitWeight(bi)/4) % 2
+		for j := i + 1; j < k; j++ {
+			c.B[i][j] = int(codeloops.BitWeight(bi&basis[j])/2) % 2
+		}
+	}
+	for l, bl := range basis {
+		c.U[l] = make([][]int, k)
+		for i, bi := range basis {
+			c.U[l][i] = make([]int, k)
+			for j := i + 1; j < k; j++ {
+				if i != l && j != l {
+					c.U[l][i][j] = parity(bi & basis[j] & bl)
+				}
+			}
+		}
+	}
+	n := cl.Size()
+	c.t = make([]uint, n)
+	for x := range c.t {
+		bit := func(i int) int { return x >> uint(i) & 1 }
+		for j := 0; j < k; j++ {
+			sum := 0
+			for i := 0; i < k; i++ {
+				sum += bit(i) * c.B[i][j]
+				for i2 := i + 1; i2 < k; i2++ {
+					sum += bit(i) * bit(i2) * c.U[j][i][i2]
+				}
+			}
+			c.t[x] |= uint(sum%2) << uint(j)
+		}
+	}
+
+	// Find s along the chain x = x' + b_j, with s = 0 on the basis, then
+	// check it gives theta everywhere.
+	theta := func(x, y int) int {
+		th, _ := cl.ThetaByIdx(uint(x), uint(y))
+		return int(th)
+	}
+	c.S = make([]int, n)
+	for x := 1; x < n; x++ {
+		top := bits.Len(uint(x)) - 1
+		x0, b := x^1<<uint(top), 1<<uint(top)
+		c.S[x] = (c.S[x0] + theta(x0, b) + c.theta0(x0, b)) % 2
+	}
+	vs := cl.VectorSpace()
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			if c.theta(x, y) != theta(x, y) {
+				return nil, fmt.Errorf("Theta is not a code cocycle for the basis at %x, %x", vs[x], vs[y])
+			}
+		}
+	}
+	return c, nil
+}
+
+func (c *cocycle) theta0(x, y int) int {
+	return parity(c.t[x] & uint(y))
+}
+
+// theta evaluates theta exactly as the GAP function does, with 0-based
+// indices.
+func (c *cocycle) theta(x, y int) int {
+	return (c.theta0(x, y) + c.S[x] + c.S[y] + c.S[x^y]) % 2
+}
+
+func writeTable(w io.Writer, name string, t [][]int) {
+	rows := make([]string, len(t))
+	for i, r := range t {
+		rows[i] = "  " + intList(r)
+	}
+	fmt.Fprintf(w, "%s := [\n%s\n];\n", name, strings.Join(rows, ",\n"))
+}
+
+// write emits the cocycle, and builds the loop from its right section,
+// which LoopByRightSection turns into the Cayley table inside GAP. The
+// script stays small, but GAP still needs room for the table.
+func (c *cocycle) write(w io.Writer) {
+	fmt.Fprintf(w, "# Theta is computed from the cubic cocycle of the basis and the signs S.\n")
+	fmt.Fprintf(w, "# Vector indices are coordinate vectors over the basis, bit i for b_i.\n")
+	fmt.Fprintf(w, "k := %d;; N := 2^k;;\n", c.k)
+	writeTable(w, "B", c.B)
+	fmt.Fprintf(w, "B := B * Z(2);;\nU := [\n")
+	for l, u := range c.U {
+		sep := ","
+		if l == len(c.U)-1 {
+			sep = ""
+		}
+		rows := make([]string, len(u))
+		for i, r := range u {
+			rows[i] = intList(r)
+		}
+		fmt.Fprintf(w, "  [%s]%s\n", strings.Join(rows, ","), sep)
+	}
+	fmt.Fprintf(w, "];;\nU := List(U, m -> m * Z(2));;\nS := %s;;\n", intList(c.S))
+	fmt.Fprintf(w, `
+Vecs := List([0..N-1], x -> List([0..k-1], i -> QuoInt(x, 2^i) mod 2) * Z(2));;
+VecIdx := v -> Sum([1..k], i -> IntFFE(v[i]) * 2^(i-1));;
+T := List(Vecs, x -> x*B + List(U, m -> x*m*x));;
+
+Mul := function(a, b)
+  local x, y, z, s;
+  x := (a - 1) mod N;; y := (b - 1) mod N;;
+  z := VecIdx(Vecs[x+1] + Vecs[y+1]);;
+  s := (QuoInt(a - 1, N) + QuoInt(b - 1, N) + IntFFE(T[x+1]*Vecs[y+1])
+    + S[x+1] + S[y+1] + S[z+1]) mod 2;;
+  return s*N + z + 1;
+end;
+
+L := LoopByRightSection([1..2*N],
+  List([1..2*N], b -> PermList(List([1..2*N], a -> Mul(a, b)))));;
+
+`)
+}
@@ -0,0 +1,75 @@
+package export
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/bnagy/codeloops"
+)
+
+func TestGAPCayley(t *testing.T) {
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: codeloops.HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	var buf bytes.Buffer
+	if err = GAP(&buf, cl, GAPOptions{}); err != nil {
+		t.Fatalf("Export failed: %s", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"LoopByCayleyTable(ct)",
+		`CheckEq("IsMoufangLoop", IsMoufangLoop(L), true);`,
+		`CheckEq("IsAssociative", IsAssociative(L), false);`,
+		`CheckEq("Center", Set(List(Center(L), Idx)), Set([1,16,17,32]));`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("Output is missing %q:\n%s", want, out)
+		}
+	}
+	// The identity must come first, so the first row is 1..32
+	if !strings.Contains(out, "ct := [\n  [1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,") {
+		t.Fatalf("First row of the Cayley table isn't the identity")
+	}
+}
+
+func TestGAPCocycle(t *testing.T) {
+	for _, p := range []codeloops.CLParams{
+		{Basis: codeloops.HammingBasis, Random: true, Seed: 3},
+		// The first 8 Golay basis vectors have no special V, W split.
+		{Basis: codeloops.GolayBasis[:8]},
+	} {
+		cl, err := codeloops.NewCL(p)
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		var buf bytes.Buffer
+		if err = GAP(&buf, cl, GAPOptions{CayleyLimit: 1}); err != nil {
+			t.Fatalf("Export of %x failed: %s", p.Basis, err)
+		}
+		out := buf.String()
+		if !strings.Contains(out, "L := LoopByRightSection(") || strings.Contains(out, "ct :=") {
+			t.Fatalf("Expected the right section construction")
+		}
+	}
+
+	// Break one theta value. It isn't a code cocycle any more.
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: codeloops.HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	d, _ := cl.Description(codeloops.ThetaBase64, false)
+	data, _ := base64.StdEncoding.DecodeString(d.Theta.Data)
+	data[12] ^= 0x10
+	d.Theta.Data = base64.StdEncoding.EncodeToString(data)
+	bad, err := d.NewCL()
+	if err != nil {
+		t.Fatalf("Failed to build loop: %s", err)
+	}
+	var buf bytes.Buffer
+	if err = GAP(&buf, bad, GAPOptions{CayleyLimit: 1}); err == nil {
+		t.Fatalf("Export should fail when theta isn't a code cocycle")
+	}
+}
@@ -0,0 +1,194 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/bnagy/codeloops"
+)
+
+// LabelStyle says how vectors are labelled in the LaTeX output. Every label
+// is typeset in math mode.
+type LabelStyle int
+
+const (
+	// LabelBinary writes the coordinates in the basis, b_1 first, so the
+	// labels of a 2 dimensional code are 00, 10, 01, 11 as in the paper.
+	LabelBinary LabelStyle = iota
+	// LabelSum writes the vector as a sum of basis vectors, eg b_1+b_3,
+	// with an underlined 0 for zero.
+	LabelSum
+	// LabelHex writes the vector itself in hex.
+	LabelHex
+	// LabelNone leaves the rows and columns unlabelled.
+	LabelNone
+)
+
+// LaTeXOptions controls ThetaTikZ and ThetaArray.
+type LaTeXOptions struct {
+	// Vectors are the rows and columns, in order. nil means the whole
+	// VectorSpace of the loop.
+	Vectors []uint
+	Labels  LabelStyle
+	// Cell is the side of one cell of the TikZ picture in cm. Zero means
+	// 0.3.
+	Cell float64
+}
+
+// VectorLabel returns the label for v in the given style, or an error if v
+// isn't in the code.
+func VectorLabel(cl *codeloops.CL, v uint, style LabelStyle) (string, error) {
+	idx, ok := cl.VectorIdxMap()[v]
+	if !ok {
+		return "", fmt.Errorf("Vector 0x%x is not in the code", v)
+	}
+	k := len(cl.Basis())
+	switch style {
+	case LabelBinary:
+		b := make([]byte, k)
+		for r := range b {
+			b[r] = '0' + byte(idx>>uint(r)&1)
+		}
+		return string(b), nil
+	case LabelSum:
+		terms := []string{}
+		for r := 0; r < k; r++ {
+			if idx>>uint(r)&1 == 1 {
+				terms = append(terms, fmt.Sprintf("b_{%d}", r+1))
+			}
+		}
+		if len(terms) == 0 {
+			return `\underline{0}`, nil
+		}
+		return strings.Join(terms, "+"), nil
+	case LabelHex:
+		return fmt.Sprintf(`\mathtt{%x}`, v), nil
+	case LabelNone:
+		return "", nil
+	}
+	return "", fmt.Errorf("Unknown label style %d", style)
+}
+
+// thetaRows collects theta restricted to the chosen vectors, and their
+// labels.
+func thetaRows(cl *codeloops.CL, opts LaTeXOptions) (rows [][]uint, labels []string, e error) {
+	vs := opts.Vectors
+	if vs == nil {
+		vs = cl.VectorSpace()
+	}
+	for _, x := range vs {
+		var l string
+		if l, e = VectorLabel(cl, x, opts.Labels); e != nil {
+			return
+		}
+		labels = append(labels, l)
+		row := make([]uint, len(vs))
+		for j, y := range vs {
+			if row[j], e = cl.ThetaByVec(x, y); e != nil {
+				return
+			}
+		}
+		rows = append(rows, row)
+	}
+	return
+}
+
+// ThetaTikZ writes a tikzpicture of theta restricted to the chosen vectors,
+// with white for 0 and black for 1, like the figures in the paper.
+func ThetaTikZ(w io.Writer, cl *codeloops.CL, opts LaTeXOptions) error {
+	rows, labels, err := thetaRows(cl, opts)
+	if err != nil {
+		return err
+	}
+	cell := opts.Cell
+	if cell == 0 {
+		cell = 0.3
+	}
+	n := len(rows)
+	bw := bufio.NewWriter(w)
+	// y points down the page, so row i is at y = i.
+	fmt.Fprintf(bw, "\\begin{tikzpicture}[x=%gcm,y=-%gcm]\n", cell, cell)
+	for i, row := range rows {
+		for j := 0; j < n; {
+			if row[j] == 0 {
+				j++
+				continue
+			}
+			start := j
+			for j < n && row[j] == 1 {
+				j++
+			}
+			fmt.Fprintf(bw, "\\fill (%d,%d) rectangle (%d,%d);\n", start, i, j, i+1)
+		}
+	}
+	fmt.Fprintf(bw, "\\draw (0,0) rectangle (%d,%d);\n", n, n)
+	if opts.Labels != LabelNone {
+		// Sums get long, so the column labels are turned on their side.
+		col := "above"
+		if opts.Labels == LabelSum {
+			col = "rotate=90,anchor=west"
+		}
+		for i, l := range labels {
+			fmt.Fprintf(bw, "\\node[left] at (0,%g) {$%s$};\n", float64(i)+0.5, l)
+			fmt.Fprintf(bw, "\\node[%s] at (%g,0) {$%s$};\n", col, float64(i)+0.5, l)
+		}
+	}
+	fmt.Fprintf(bw, "\\end{tikzpicture}\n")
+	return bw.Flush()
+}
+
+// ThetaArray writes theta restricted to the chosen vectors as an array
+// environment, for use in math mode.
+func ThetaArray(w io.Writer, cl *codeloops.CL, opts LaTeXOptions) error {
+	rows, labels, err := thetaRows(cl, opts)
+	if err != nil {
+		return err
+	}
+	labelled := opts.Labels != LabelNone
+	bw := bufio.NewWriter(w)
+	spec := strings.Repeat("c", len(rows))
+	if labelled {
+		spec = "c|" + spec
+	}
+	fmt.Fprintf(bw, "\\begin{array}{%s}\n", spec)
+	if labelled {
+		fmt.Fprintf(bw, " & %s \\\\\n\\hline\n", strings.Join(labels, " & "))
+	}
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for j, th := range row {
+			cells[j] = fmt.Sprint(th)
+		}
+		if labelled {
+			cells = append([]string{labels[i]}, cells...)
+		}
+		fmt.Fprintf(bw, "%s \\\\\n", strings.Join(cells, " & "))
+	}
+	fmt.Fprintf(bw, "\\end{array}\n")
+	return bw.Flush()
+}
+
+// BasisTable writes the basis as a tabular, one row per vector: its name,
+// the binary expansion padded to the code length (most significant bit
+// first, as PrintBasis does), the hex value and the weight.
+func BasisTable(w io.Writer, basis []uint) error {
+	n := int(codeloops.CodeLength(basis))
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "\\begin{tabular}{lllr}\n & binary & hex & weight \\\\\n\\hline\n")
+	for i, b := range basis {
+		bin := fmt.Sprintf("%0*b", n, b)
+		// Group the bits in fours from the right, like the hex digits.
+		groups := []string{}
+		for len(bin) > 4 {
+			groups = append([]string{bin[len(bin)-4:]}, groups...)
+			bin = bin[:len(bin)-4]
+		}
+		groups = append([]string{bin}, groups...)
+		fmt.Fprintf(bw, "$b_{%d}$ & \\texttt{%s} & \\texttt{%x} & %d \\\\\n",
+			i+1, strings.Join(groups, `\,`), b, codeloops.BitWeight(b))
+	}
+	fmt.Fprintf(bw, "\\end{tabular}\n")
+	return bw.Flush()
+}
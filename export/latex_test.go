@@ -0,0 +1,81 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bnagy/codeloops"
+)
+
+func TestThetaArray(t *testing.T) {
+	// The code loop of Q8, which has the cocycle of Figure 1 in the paper.
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: []uint{0x0f, 0x3c}})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	var buf bytes.Buffer
+	if err = ThetaArray(&buf, cl, LaTeXOptions{}); err != nil {
+		t.Fatalf("Export failed: %s", err)
+	}
+	want := `\begin{array}{c|cccc}
+ & 00 & 10 & 01 & 11 \\
+\hline
+00 & 0 & 0 & 0 & 0 \\
+10 & 0 & 1 & 1 & 0 \\
+01 & 0 & 0 & 1 & 1 \\
+11 & 0 & 1 & 0 & 1 \\
+\end{array}
+`
+	if buf.String() != want {
+		t.Fatalf("Expected\n%s\ngot\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	opts := LaTeXOptions{Vectors: []uint{0x3c, 0x33}, Labels: LabelSum}
+	if err = ThetaArray(&buf, cl, opts); err != nil {
+		t.Fatalf("Export failed: %s", err)
+	}
+	if !strings.Contains(buf.String(), " & b_{2} & b_{1}+b_{2} \\\\") {
+		t.Fatalf("Bad sum labels:\n%s", buf.String())
+	}
+	opts.Vectors = []uint{0x1}
+	if err = ThetaArray(&buf, cl, opts); err == nil {
+		t.Fatalf("Expected an error for a vector not in the code")
+	}
+}
+
+func TestThetaTikZ(t *testing.T) {
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: []uint{0x0f, 0x3c}})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	var buf bytes.Buffer
+	if err = ThetaTikZ(&buf, cl, LaTeXOptions{Labels: LabelHex}); err != nil {
+		t.Fatalf("Export failed: %s", err)
+	}
+	for _, want := range []string{
+		"\\begin{tikzpicture}[x=0.3cm,y=-0.3cm]\n",
+		"\\fill (1,1) rectangle (3,2);\n", // the run 0110 in row 10
+		"\\draw (0,0) rectangle (4,4);\n",
+		"\\node[above] at (3.5,0) {$\\mathtt{33}$};\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("Output is missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestBasisTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := BasisTable(&buf, codeloops.HammingBasis); err != nil {
+		t.Fatalf("Export failed: %s", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) != 3+len(codeloops.HammingBasis)+2 {
+		t.Fatalf("Wrong number of lines:\n%s", buf.String())
+	}
+	if !strings.HasPrefix(lines[3], "$b_{1}$ & \\texttt{") || !strings.HasSuffix(lines[3], "& 4 \\\\") {
+		t.Fatalf("Bad first row %q", lines[3])
+	}
+}
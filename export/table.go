@@ -0,0 +1,216 @@
+package export
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/bnagy/codeloops"
+)
+
+// Table is a 2D table computed from a code loop, ready to be written as
+// .npy or CSV for analysis elsewhere (eg numpy.load or pandas.read_csv).
+// Entries are stored row by row.
+type Table struct {
+	Name string
+	Rows []uint // vector labelling each row
+	Cols []uint // vector labelling each column
+	Data []int32
+	// Bits is true when every entry is 0 or 1, so the table can be written
+	// as uint8 or bit-packed.
+	Bits  bool
+	basis []uint
+	about string
+}
+
+// ThetaTable returns theta on the whole VectorSpace, in VectorSpace order.
+func ThetaTable(cl *codeloops.CL) *Table {
+	vs := cl.VectorSpace()
+	t := &Table{Name: "theta", Rows: vs, Cols: vs, Bits: true, basis: cl.Basis(),
+		about: "theta(row, col), vectors in VectorSpace order: index bit r is the coefficient of basis vector r"}
+	for i := range vs {
+		for j := range vs {
+			th, _ := cl.ThetaByIdx(uint(i), uint(j))
+			t.Data = append(t.Data, int32(th))
+		}
+	}
+	return t
+}
+
+// AlphaTable returns theta restricted to V∪W, in the order of
+// RestrictedTheta: V, then W without its zero vector.
+func AlphaTable(cl *codeloops.CL) *Table {
+	basis := cl.Basis()
+	h := len(basis) / 2
+	ord := append(codeloops.VectorSpace(basis[:h]), codeloops.VectorSpace(basis[h:])[1:]...)
+	t := &Table{Name: "alpha", Rows: ord, Cols: ord, Bits: true, basis: basis,
+		about: "theta(row, col) on V∪W: V = span of the first half of the basis in VectorSpace order, then W = span of the second half without 0"}
+	for _, row := range cl.RestrictedTheta() {
+		for _, th := range row {
+			t.Data = append(t.Data, int32(th))
+		}
+	}
+	return t
+}
+
+// MulTable returns the products of the positive elements, in VectorSpace
+// order. The entry for x·y = ±z is ±(k+1), where k is the index of z, so
+// the sign of the entry is the sign of the product. Products involving
+// negative elements just flip signs, so they are left out.
+func MulTable(cl *codeloops.CL) *Table {
+	vs := cl.VectorSpace()
+	t := &Table{Name: "mul", Rows: vs, Cols: vs, basis: cl.Basis(),
+		about: "x_row * x_col = ±x_k is stored as ±(k+1), vectors in VectorSpace order"}
+	elems := cl.LoopElems()[:len(vs)]
+	vm := cl.VectorIdxMap()
+	res := new(codeloops.CLElem)
+	for i := range elems {
+		for j := range elems {
+			cl.Mul(&elems[i], &elems[j], res)
+			k := int32(vm[res.Vec()]) + 1
+			if res.Sign() == codeloops.Neg {
+				k = -k
+			}
+			t.Data = append(t.Data, k)
+		}
+	}
+	return t
+}
+
+// npyHeader builds a version 1.0 .npy header, padded so the data starts on
+// a 64 byte boundary.
+func npyHeader(descr string, rows, cols int) []byte {
+	dict := fmt.Sprintf("{'descr': '%s', 'fortran_order': False, 'shape': (%d, %d), }", descr, rows, cols)
+	total := 10 + len(dict) + 1
+	pad := (64 - total%64) % 64
+	dict += strings.Repeat(" ", pad) + "\n"
+	hdr := []byte("\x93NUMPY\x01\x00\x00\x00")
+	binary.LittleEndian.PutUint16(hdr[8:], uint16(len(dict)))
+	return append(hdr, dict...)
+}
+
+// WriteNPY writes the table as a 2D .npy array. Bit tables are uint8, or
+// with packed set, bit-packed along each row as by numpy.packbits, so
+// numpy.unpackbits(a, axis=1, count=len(Cols)) recovers them. Other tables
+// are little endian int32, and can't be packed.
+func (t *Table) WriteNPY(w io.Writer, packed bool) error {
+	bw := bufio.NewWriter(w)
+	nr, nc := len(t.Rows), len(t.Cols)
+	switch {
+	case packed && !t.Bits:
+		return fmt.Errorf("Table %s isn't a bit table, so it can't be packed", t.Name)
+	case packed:
+		bw.Write(npyHeader("|u1", nr, (nc+7)/8))
+		row := make([]byte, (nc+7)/8)
+		for i := 0; i < nr; i++ {
+			for b := range row {
+				row[b] = 0
+			}
+			for j, x := range t.Data[i*nc : (i+1)*nc] {
+				row[j/8] |= byte(x) << uint(7-j%8)
+			}
+			bw.Write(row)
+		}
+	case t.Bits:
+		bw.Write(npyHeader("|u1", nr, nc))
+		for _, x := range t.Data {
+			bw.WriteByte(byte(x))
+		}
+	default:
+		bw.Write(npyHeader("<i4", nr, nc))
+		var buf [4]byte
+		for _, x := range t.Data {
+			binary.LittleEndian.PutUint32(buf[:], uint32(x))
+			bw.Write(buf[:])
+		}
+	}
+	return bw.Flush()
+}
+
+// WriteCSV writes the table with a header row of column vectors, and the
+// row vector as the first field of each row. Vectors are in hex.
+func (t *Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	rec := append([]string{t.Name}, codeloops.HexStrings(t.Cols)...)
+	cw.Write(rec)
+	nc := len(t.Cols)
+	for i, v := range t.Rows {
+		rec = rec[:0]
+		rec = append(rec, fmt.Sprintf("0x%x", v))
+		for _, x := range t.Data[i*nc : (i+1)*nc] {
+			rec = append(rec, strconv.Itoa(int(x)))
+		}
+		cw.Write(rec)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// Sidecar is the JSON file written next to a table, describing how to read
+// it. Vectors are hex strings, as in a LoopDescription.
+type Sidecar struct {
+	Table       string   `json:"table"`
+	File        string   `json:"file"`
+	Format      string   `json:"format"` // "npy", "npy-packed" or "csv"
+	DType       string   `json:"dtype"`
+	Shape       []int    `json:"shape"`
+	Description string   `json:"description"`
+	Basis       []string `json:"basis"`
+	Rows        []string `json:"rows"`
+	Cols        []string `json:"cols"`
+}
+
+// Sidecar formats.
+const (
+	FormatNPY       = "npy"
+	FormatNPYPacked = "npy-packed"
+	FormatCSV       = "csv"
+)
+
+// Sidecar returns the description of the table as written in format to
+// file.
+func (t *Table) Sidecar(file, format string) (*Sidecar, error) {
+	s := &Sidecar{
+		Table:       t.Name,
+		File:        file,
+		Format:      format,
+		Shape:       []int{len(t.Rows), len(t.Cols)},
+		Description: t.about,
+		Basis:       codeloops.HexStrings(t.basis),
+		Rows:        codeloops.HexStrings(t.Rows),
+		Cols:        codeloops.HexStrings(t.Cols),
+	}
+	switch format {
+	case FormatNPY, FormatCSV:
+		s.DType = "int32"
+		if t.Bits {
+			s.DType = "uint8"
+		}
+	case FormatNPYPacked:
+		if !t.Bits {
+			return nil, fmt.Errorf("Table %s isn't a bit table, so it can't be packed", t.Name)
+		}
+		// DType and Shape are those of the unpacked table, which is what
+		// unpackbits needs.
+		s.DType = "uint8"
+	default:
+		return nil, fmt.Errorf("Unknown table format %q", format)
+	}
+	return s, nil
+}
+
+// WriteSidecar writes the JSON sidecar for the table.
+func (t *Table) WriteSidecar(w io.Writer, file, format string) error {
+	s, err := t.Sidecar(file, format)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bnagy/codeloops"
+)
+
+// readNPY checks the header of a .npy file and returns its dict and data.
+func readNPY(t *testing.T, b []byte) (string, []byte) {
+	if !bytes.HasPrefix(b, []byte("\x93NUMPY\x01\x00")) {
+		t.Fatalf("Bad npy magic % x", b[:8])
+	}
+	n := int(binary.LittleEndian.Uint16(b[8:]))
+	if (10+n)%64 != 0 || b[10+n-1] != '\n' {
+		t.Fatalf("npy data isn't aligned")
+	}
+	return strings.TrimSpace(string(b[10 : 10+n])), b[10+n:]
+}
+
+func TestTableNPY(t *testing.T) {
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: codeloops.HammingBasis, Random: true, Seed: 7})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	theta := ThetaTable(cl)
+	var buf bytes.Buffer
+	if err = theta.WriteNPY(&buf, false); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	dict, data := readNPY(t, append([]byte{}, buf.Bytes()...))
+	if dict != "{'descr': '|u1', 'fortran_order': False, 'shape': (16, 16), }" || len(data) != 256 {
+		t.Fatalf("Bad npy header %q or length %d", dict, len(data))
+	}
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 16; j++ {
+			th, _ := cl.ThetaByIdx(uint(i), uint(j))
+			if uint(data[i*16+j]) != th {
+				t.Fatalf("Wrong theta at %d, %d", i, j)
+			}
+		}
+	}
+
+	buf.Reset()
+	if err = theta.WriteNPY(&buf, true); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	dict, packed := readNPY(t, buf.Bytes())
+	if !strings.Contains(dict, "'shape': (16, 2)") || len(packed) != 32 {
+		t.Fatalf("Bad packed npy header %q", dict)
+	}
+	for i := 0; i < 16; i++ {
+		for j := 0; j < 16; j++ {
+			if uint(packed[i*2+j/8]>>uint(7-j%8)&1) != uint(data[i*16+j]) {
+				t.Fatalf("Packed bit %d, %d differs", i, j)
+			}
+		}
+	}
+
+	mul := MulTable(cl)
+	if err = mul.WriteNPY(&buf, true); err == nil {
+		t.Fatalf("Expected an error packing the multiplication table")
+	}
+	buf.Reset()
+	mul.WriteNPY(&buf, false)
+	dict, data = readNPY(t, buf.Bytes())
+	if !strings.Contains(dict, "'<i4'") || len(data) != 4*256 {
+		t.Fatalf("Bad mul npy header %q", dict)
+	}
+	// x·x = ±0, and the sign is theta(x, x)
+	for i := 0; i < 16; i++ {
+		got := int32(binary.LittleEndian.Uint32(data[4*(i*16+i):]))
+		th, _ := cl.ThetaByIdx(uint(i), uint(i))
+		if want := int32(1 - 2*int(th)); got != want {
+			t.Fatalf("Expected %d for x%d squared, got %d", want, i, got)
+		}
+	}
+}
+
+func TestTableCSV(t *testing.T) {
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: codeloops.HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	alpha := AlphaTable(cl)
+	var buf bytes.Buffer
+	if err = alpha.WriteCSV(&buf); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	recs, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Bad CSV: %s", err)
+	}
+	// V and W have 4 vectors each, sharing 0
+	if len(recs) != 8 || len(recs[0]) != 8 || recs[0][0] != "alpha" || recs[1][0] != "0x0" {
+		t.Fatalf("Bad CSV layout %v", recs)
+	}
+	rt := cl.RestrictedTheta()
+	for i := range rt {
+		for j := range rt {
+			if recs[i+1][j+1] != string('0'+byte(rt[i][j])) {
+				t.Fatalf("Wrong alpha at %d, %d", i, j)
+			}
+		}
+	}
+
+	buf.Reset()
+	if err = alpha.WriteSidecar(&buf, "alpha.csv", FormatCSV); err != nil {
+		t.Fatalf("Sidecar failed: %s", err)
+	}
+	var s Sidecar
+	if err = json.Unmarshal(buf.Bytes(), &s); err != nil {
+		t.Fatalf("Bad sidecar JSON: %s", err)
+	}
+	if s.DType != "uint8" || s.Shape[0] != 7 || len(s.Rows) != 7 || len(s.Basis) != 4 {
+		t.Fatalf("Bad sidecar %+v", s)
+	}
+	if _, err = MulTable(cl).Sidecar("mul.npy", FormatNPYPacked); err == nil {
+		t.Fatalf("Expected an error for a packed sidecar of the multiplication table")
+	}
+}
@@ -0,0 +1,188 @@
+package codeloops
+
+import (
+	"fmt"
+)
+
+// Constructors for some standard families of codes, so loops of many sizes
+// can be built without typing in bases. Coordinates are numbered from 0 as
+// in LinearCode, coordinate i being bit n-1-i, and every code has to fit in
+// a uint.
+
+// word returns the vector of length n with 1s at the given coordinates.
+func word(n uint, coords ...uint) (v uint) {
+	for _, c := range coords {
+		v |= 1 << (n - 1 - c)
+	}
+	return
+}
+
+// ReedMuller returns the Reed-Muller code RM(r,m), of length 2^m,
+// dimension sum_{i<=r} C(m,i) and minimum distance 2^(m-r). Coordinate p is
+// the point of F_2^m whose bits are those of p, and the basis is the
+// monomials of degree at most r in order of degree. All the weights are
+// divisible by 2^(ceil(m/r)-1), so for r >= 1 the code is doubly even
+// exactly when m > 2r, eg RM(1,3) is e8 and RM(2,5) is a [32,16,8] code.
+func ReedMuller(r, m uint) (*LinearCode, error) {
+	n := uint(1) << m
+	if r > m || n > uintBits {
+		return nil, fmt.Errorf("RM(%d,%d) doesn't exist or doesn't fit in a uint", r, m)
+	}
+	basis := []uint{}
+	for deg := uint(0); deg <= r; deg++ {
+		// monomials of degree deg are the variable masks of that weight
+		for mono := uint(0); mono < n; mono++ {
+			if BitWeight(mono) != deg {
+				continue
+			}
+			var v uint
+			for p := uint(0); p < n; p++ {
+				if p&mono == mono {
+					v |= word(n, p)
+				}
+			}
+			basis = append(basis, v)
+		}
+	}
+	return NewLinearCode(basis, n)
+}
+
+func isPrime(p uint) bool {
+	if p < 2 {
+		return false
+	}
+	for d := uint(2); d*d <= p; d++ {
+		if p%d == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// ExtendedQR returns the extended quadratic residue code of length p+1. It
+// is doubly even and self dual when p is a prime with p = -1 mod 8, which
+// (for lengths that fit) gives lengths 8, 24, 32 and 48. Length 8 is e8 and
+// length 24 is the Golay code.
+//
+// The QR code is cyclic, so it is spanned by the cyclic shifts of its
+// idempotent, which is the sum of x^i over either the residues or the
+// non-residues, possibly plus 1. Rather than work out which, each is tried
+// and the one whose extension is doubly even is kept.
+func ExtendedQR(p uint) (*LinearCode, error) {
+	if !isPrime(p) || p%8 != 7 {
+		return nil, fmt.Errorf("The extended QR code of length %d isn't doubly even", p+1)
+	}
+	n := p + 1
+	if n > uintBits {
+		return nil, fmt.Errorf("Length %d doesn't fit in a uint", n)
+	}
+	residue := make([]bool, p)
+	for i := uint(1); i < p; i++ {
+		residue[i*i%p] = true
+	}
+	for _, pick := range []bool{true, false} {
+		for _, one := range []bool{false, true} {
+			coords := []uint{}
+			if one {
+				coords = append(coords, 0)
+			}
+			for i := uint(1); i < p; i++ {
+				if residue[i] == pick {
+					coords = append(coords, i)
+				}
+			}
+			// The cyclic shifts, extended by an overall parity bit.
+			span := []uint{}
+			for s := uint(0); s < p; s++ {
+				shifted := []uint{}
+				for _, c := range coords {
+					shifted = append(shifted, (c+s)%p)
+				}
+				if len(shifted)%2 == 1 {
+					shifted = append(shifted, p)
+				}
+				span = append(span, word(n, shifted...))
+			}
+			basis := independentSubset(span)
+			if uint(len(basis)) != n/2 {
+				continue
+			}
+			c, err := NewLinearCode(basis, n)
+			if err == nil && c.IsDoublyEven() {
+				return c, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("Failed to find the QR code of length %d", p)
+}
+
+// independentSubset returns a maximal independent subset of vs, keeping
+// the first of any dependent vectors.
+func independentSubset(vs []uint) (basis []uint) {
+	for _, v := range vs {
+		if dependentVector(append(basis, v)) < 0 {
+			basis = append(basis, v)
+		}
+	}
+	return
+}
+
+// D returns the code d_n of even length n >= 4, spanned by the overlapping
+// tetrads 1111 0000..., 0011 1100..., ..., ...0000 1111. It has dimension
+// n/2-1, minimum distance 4, and is doubly even.
+func D(n uint) (*LinearCode, error) {
+	if n < 4 || n%2 != 0 || n > uintBits {
+		return nil, fmt.Errorf("d%d needs an even length from 4 to %d", n, uintBits)
+	}
+	basis := []uint{}
+	for i := uint(0); i+4 <= n; i += 2 {
+		basis = append(basis, word(n, i, i+1, i+2, i+3))
+	}
+	return NewLinearCode(basis, n)
+}
+
+// DPlus returns the self dual code d_n^+, which is d_n glued with
+// 0101...01. That has weight n/2, so the code is doubly even for lengths
+// divisible by 8. d8^+ is another copy of e8, and d16^+ and e8+e8 are the
+// two doubly even self dual codes of length 16.
+func DPlus(n uint) (*LinearCode, error) {
+	if n%8 != 0 {
+		return nil, fmt.Errorf("d%d+ is only doubly even for lengths divisible by 8", n)
+	}
+	d, err := D(n)
+	if err != nil {
+		return nil, err
+	}
+	glue := uint(0)
+	for i := uint(1); i < n; i += 2 {
+		glue |= word(n, i)
+	}
+	return NewLinearCode(append(d.Basis(), glue), n)
+}
+
+// E8 returns the [8,4,4] extended Hamming code, as RM(1,3).
+func E8() *LinearCode {
+	c, _ := ReedMuller(1, 3)
+	return c
+}
+
+// DirectSum returns the direct sum of the codes, with the coordinates of
+// the first code first.
+func DirectSum(codes ...*LinearCode) (*LinearCode, error) {
+	n := uint(0)
+	for _, c := range codes {
+		n += c.Length()
+	}
+	if n > uintBits {
+		return nil, fmt.Errorf("Direct sum of length %d doesn't fit in a uint", n)
+	}
+	basis := []uint{}
+	rest := n
+	for _, c := range codes {
+		rest -= c.Length()
+		for _, v := range c.Basis() {
+			basis = append(basis, v<<rest)
+		}
+	}
+	return NewLinearCode(basis, n)
+}
@@ -0,0 +1,112 @@
+package codeloops
+
+import (
+	"testing"
+)
+
+func binomial(n, k uint) uint {
+	r := uint(1)
+	for i := uint(0); i < k; i++ {
+		r = r * (n - i) / (i + 1)
+	}
+	return r
+}
+
+func TestReedMuller(t *testing.T) {
+	for m := uint(1); m <= 6; m++ {
+		for r := uint(0); r <= m; r++ {
+			c, err := ReedMuller(r, m)
+			if err != nil {
+				t.Fatalf("Failed to make RM(%d,%d): %s", r, m, err)
+			}
+			if c.Dimension() > 24 {
+				continue // too many words to count
+			}
+			k := uint(0)
+			for i := uint(0); i <= r; i++ {
+				k += binomial(m, i)
+			}
+			if c.Length() != 1<<m || c.Dimension() != k || c.MinimumDistance() != 1<<(m-r) {
+				t.Fatalf("RM(%d,%d) is [%d,%d,%d]", r, m, c.Length(), c.Dimension(), c.MinimumDistance())
+			}
+			de := m >= 2 && (r == 0 || m > 2*r)
+			if c.IsDoublyEven() != de {
+				t.Fatalf("RM(%d,%d) doubly even is %v, expected %v", r, m, c.IsDoublyEven(), de)
+			}
+		}
+	}
+	if _, err := ReedMuller(1, 7); err == nil {
+		t.Fatalf("RM(1,7) doesn't fit")
+	}
+}
+
+func TestExtendedQR(t *testing.T) {
+	golay, _ := NewLinearCode(GolayBasis, 24)
+	for _, c := range []struct{ p, d uint }{{7, 4}, {23, 8}, {31, 8}, {47, 12}} {
+		code, err := ExtendedQR(c.p)
+		if err != nil {
+			t.Fatalf("Failed to make QR%d: %s", c.p+1, err)
+		}
+		if code.Dimension() != (c.p+1)/2 || code.MinimumDistance() != c.d {
+			t.Fatalf("QR%d is [%d,%d,%d]", c.p+1, code.Length(), code.Dimension(), code.MinimumDistance())
+		}
+		if !code.IsDoublyEven() || !code.IsSelfDual() {
+			t.Fatalf("QR%d should be doubly even and self dual", c.p+1)
+		}
+		if c.p == 23 && !equalUints(code.WeightDistribution(), golay.WeightDistribution()) {
+			t.Fatalf("QR24 should have the Golay weight distribution")
+		}
+	}
+	if _, err := ExtendedQR(17); err == nil {
+		t.Fatalf("QR18 isn't doubly even")
+	}
+}
+
+func TestDCodes(t *testing.T) {
+	for _, n := range []uint{4, 6, 10, 24} {
+		c, err := D(n)
+		if err != nil {
+			t.Fatalf("Failed to make d%d: %s", n, err)
+		}
+		if c.Dimension() != n/2-1 || c.MinimumDistance() != 4 || !c.IsDoublyEven() {
+			t.Fatalf("d%d is [%d,%d,%d]", n, c.Length(), c.Dimension(), c.MinimumDistance())
+		}
+	}
+	e8 := E8()
+	d8, _ := DPlus(8)
+	if !equalUints(e8.WeightDistribution(), d8.WeightDistribution()) {
+		t.Fatalf("d8+ should look like e8")
+	}
+	// The two doubly even self dual codes of length 16 have the same
+	// weight distribution, but d16+ is indecomposable.
+	d16, err := DPlus(16)
+	if err != nil {
+		t.Fatalf("Failed to make d16+: %s", err)
+	}
+	e8e8, err := DirectSum(e8, e8)
+	if err != nil {
+		t.Fatalf("Failed to make e8+e8: %s", err)
+	}
+	wd := []uint{1, 0, 0, 0, 28, 0, 0, 0, 198, 0, 0, 0, 28, 0, 0, 0, 1}
+	for _, c := range []*LinearCode{d16, e8e8} {
+		if !c.IsDoublyEven() || !c.IsSelfDual() || !equalUints(c.WeightDistribution(), wd) {
+			t.Fatalf("Expected a doubly even self dual code with weights %v, got %v", wd, c.WeightDistribution())
+		}
+	}
+	// e8+e8 has 2*14 weight 4 words inside the halves; d16+ has words
+	// straddling the middle.
+	if !d16.Contains(word(16, 6, 7, 8, 9)) || e8e8.Contains(word(16, 6, 7, 8, 9)) {
+		t.Fatalf("Expected a weight 4 word across the halves only in d16+")
+	}
+	if _, err = DPlus(12); err == nil {
+		t.Fatalf("d12+ isn't doubly even")
+	}
+
+	// Loops from all of them.
+	for _, c := range []*LinearCode{e8, d16, e8e8} {
+		cl, err := c.NewCL(false, 0)
+		if err != nil || cl.VerifyBasis() != nil {
+			t.Fatalf("Failed to build a loop: %v", err)
+		}
+	}
+}
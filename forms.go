@@ -0,0 +1,263 @@
+package codeloops
+
+import (
+	"fmt"
+)
+
+// In a code loop the cocycle is pinned down, up to a coboundary, by three
+// forms on the code ([Gri86] p. 225):
+//
+//	(S) theta(x,x) = |x|/4
+//	(C) theta(x,y) + theta(y,x) = |x&y|/2
+//	(A) theta(x,y) + theta(x+y,z) + theta(y,z) + theta(x,y+z) = |x&y&z|
+//
+// (all mod 2). The associator |x&y&z| is trilinear, so it vanishes
+// everywhere iff it vanishes on basis triples. The commutator is not quite
+// bilinear, since
+//
+//	|(x+z)&y|/2 = |x&y|/2 + |z&y|/2 + |x&y&z|
+//
+// but once the associator is zero it is, so the loop is commutative iff it
+// is associative and the commutator vanishes on basis pairs. This lets us
+// decide both questions in O(k^3) from the basis alone, instead of looking
+// at every triple of vectors.
+
+// The weight of a sum is also a polynomial in the intersections:
+//
+//	|x+y| = |x| + |y| - 2|x&y|
+//	|x+y+z| = |x| + |y| + |z| - 2(|x&y| + |x&z| + |y&z|) + 4|x&y&z|
+//
+// so the code is doubly even iff every basis vector has weight 0 mod 4 and
+// every pair meets evenly, and triply even iff the weights are 0 mod 8,
+// the pairs meet in 0 mod 4 and the triples meet evenly. That's O(k^2)
+// (resp. O(k^3)) instead of 2^k, and when a condition fails, the sum of the
+// basis vectors involved is a word with bad weight. None of this needs the
+// basis to be independent.
+
+// DivisibilityError is returned by CheckDoublyEven and CheckTriplyEven. It
+// names the basis vectors whose sum, Word, has a weight which isn't a
+// multiple of Modulus.
+type DivisibilityError struct {
+	Indices []int
+	Word    uint
+	Weight  uint
+	Modulus uint
+}
+
+func (e *DivisibilityError) Error() string {
+	return fmt.Sprintf("Bad vector %b (sum of basis vectors %v), bitweight %d not a multiple of %d", e.Word, e.Indices, e.Weight, e.Modulus)
+}
+
+func divisibilityError(basis []uint, mod uint, indices ...int) *DivisibilityError {
+	var w uint
+	for _, i := range indices {
+		w ^= basis[i]
+	}
+	return &DivisibilityError{Indices: indices, Word: w, Weight: BitWeight(w), Modulus: mod}
+}
+
+// CheckDoublyEven checks that the code spanned by basis is doubly even,
+// returning a *DivisibilityError with a basis vector or pair if it isn't.
+func CheckDoublyEven(basis []uint) error {
+	for i, v := range basis {
+		if BitWeight(v)%4 != 0 {
+			return divisibilityError(basis, 4, i)
+		}
+	}
+	for i := 0; i < len(basis); i++ {
+		for j := i + 1; j < len(basis); j++ {
+			if BitWeight(basis[i]&basis[j])%2 != 0 {
+				return divisibilityError(basis, 4, i, j)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckTriplyEven checks that the code spanned by basis has every weight
+// divisible by 8, returning a *DivisibilityError with a basis vector, pair
+// or triple if it doesn't.
+func CheckTriplyEven(basis []uint) error {
+	for i, v := range basis {
+		if BitWeight(v)%8 != 0 {
+			return divisibilityError(basis, 8, i)
+		}
+	}
+	for i := 0; i < len(basis); i++ {
+		for j := i + 1; j < len(basis); j++ {
+			if BitWeight(basis[i]&basis[j])%4 != 0 {
+				return divisibilityError(basis, 8, i, j)
+			}
+		}
+	}
+	for i := 0; i < len(basis); i++ {
+		for j := i + 1; j < len(basis); j++ {
+			for k := j + 1; k < len(basis); k++ {
+				if BitWeight(basis[i]&basis[j]&basis[k])%2 != 0 {
+					return divisibilityError(basis, 8, i, j, k)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// IsAssocCode reports whether the code loop built from a doubly even basis
+// is associative, ie a group.
+func IsAssocCode(basis []uint) bool {
+	for i := 0; i < len(basis); i++ {
+		for j := i + 1; j < len(basis); j++ {
+			for k := j + 1; k < len(basis); k++ {
+				if BitWeight(basis[i]&basis[j]&basis[k])%2 != 0 {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+// IsCommutativeCode reports whether the code loop built from a doubly even
+// basis is commutative. Commutative code loops are always associative.
+func IsCommutativeCode(basis []uint) bool {
+	if !IsAssocCode(basis) {
+		return false
+	}
+	for i := 0; i < len(basis); i++ {
+		for j := i + 1; j < len(basis); j++ {
+			if (BitWeight(basis[i]&basis[j])/2)%2 != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// verifyAssoc3 decides associativity from the basis. It is only valid when
+// theta is known to be a code cocycle.
+func (cl *CL) verifyAssoc3() bool {
+	return IsAssocCode(cl.basis)
+}
+
+// verifyCommutative is the brute force check that theta(x,y) = theta(y,x)
+// for all x, y.
+func (cl *CL) verifyCommutative() bool {
+	for i := uint(0); i < cl.size; i++ {
+		for j := i + 1; j < cl.size; j++ {
+			if cl.thetaByIdxFast(i, j) != cl.thetaByIdxFast(j, i) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// verifyCommutative2 decides commutativity from the basis. It is only valid
+// when theta is known to be a code cocycle.
+func (cl *CL) verifyCommutative2() bool {
+	return IsCommutativeCode(cl.basis)
+}
+
+// IsCommutative checks whether the loop is commutative.
+func (cl *CL) IsCommutative() bool {
+	if cl.cocycle {
+		return cl.verifyCommutative2()
+	}
+	return cl.verifyCommutative()
+}
+
+// verifyCodeCocycle checks normalization, then the axioms (S), (C), (A) over
+// every vector, pair and triple. This is the slow reference version.
+func (cl *CL) verifyCodeCocycle() error {
+	vs := cl.VectorSpace()
+
+	// theta(0,x) == theta(x,0) == 0 (normalized cocycle)
+	for i := uint(0); i < cl.size; i++ {
+		if cl.thetaByIdxFast(i, 0) != 0 || cl.thetaByIdxFast(0, i) != 0 {
+			return fmt.Errorf("Theta not normalized at %x", vs[i])
+		}
+	}
+
+	// (S) for all x, theta(x,x) === |x|/4
+	for i := uint(0); i < cl.size; i++ {
+		if cl.thetaByIdxFast(i, i) != (BitWeight(vs[i])/4)%2 {
+			return fmt.Errorf("Theta fails (S) at %x", vs[i])
+		}
+	}
+
+	// (C) for all x,y, theta(x,y) + theta(y,x) === |x&y|/2
+	for i := uint(0); i < cl.size; i++ {
+		for j := uint(0); j < cl.size; j++ {
+			if cl.thetaByIdxFast(i, j)^cl.thetaByIdxFast(j, i) != (BitWeight(vs[i]&vs[j])/2)%2 {
+				return fmt.Errorf("Theta fails (C) at %x, %x", vs[i], vs[j])
+			}
+		}
+	}
+
+	// (A) for all x,y,z theta(x,y) + theta(x^y,z) + theta(y,z) + theta(x,y^z) === |x&y&z|
+	for i := uint(0); i < cl.size; i++ {
+		for j := uint(0); j < cl.size; j++ {
+			for k := uint(0); k < cl.size; k++ {
+				if cl.failsA(i, j, k) {
+					return fmt.Errorf("Theta fails (A) at %x, %x, %x", vs[i], vs[j], vs[k])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// failsA checks (A) for the vectors with indices i, j, k. Since the vector
+// space is built from the index bits, vs[i]^vs[j] == vs[i^j].
+func (cl *CL) failsA(i, j, k uint) bool {
+	lhs := cl.thetaByIdxFast(i, j) ^ cl.thetaByIdxFast(i^j, k) ^
+		cl.thetaByIdxFast(j, k) ^ cl.thetaByIdxFast(i, j^k)
+	return lhs != BitWeight(cl.vs[i]&cl.vs[j]&cl.vs[k])%2
+}
+
+// verifyCodeCocycle2 is the same check, but only looks at triples whose
+// last element is a basis vector, so it is O(k*N^2) instead of O(N^3).
+//
+// This is enough: write f(x,y,z) for the difference between the two sides
+// of (A). Both sides are 3-cocycles (the left is a coboundary, the right is
+// trilinear), so f satisfies
+//
+//	f(x,y,z) + f(w+x,y,z) + f(w,x+y,z) + f(w,x,y+z) + f(w,x,y) = 0
+//
+// Taking (w,x,y,z) -> (x,y,z',b) and inducting on the number of basis
+// vectors in z = z'+b, f vanishes everywhere if it vanishes whenever the
+// last argument is a basis vector (it always vanishes when it is 0, for a
+// normalized theta).
+func (cl *CL) verifyCodeCocycle2() error {
+	vs := cl.VectorSpace()
+
+	for i := uint(0); i < cl.size; i++ {
+		if cl.thetaByIdxFast(i, 0) != 0 || cl.thetaByIdxFast(0, i) != 0 {
+			return fmt.Errorf("Theta not normalized at %x", vs[i])
+		}
+		if cl.thetaByIdxFast(i, i) != (BitWeight(vs[i])/4)%2 {
+			return fmt.Errorf("Theta fails (S) at %x", vs[i])
+		}
+	}
+	for i := uint(0); i < cl.size; i++ {
+		for j := uint(0); j < cl.size; j++ {
+			if cl.thetaByIdxFast(i, j)^cl.thetaByIdxFast(j, i) != (BitWeight(vs[i]&vs[j])/2)%2 {
+				return fmt.Errorf("Theta fails (C) at %x, %x", vs[i], vs[j])
+			}
+		}
+	}
+	i, j, b, found := firstTriple(int(cl.size), int(cl.size), int(cl.basisLen), func(i, j, b int) bool {
+		return cl.failsA(uint(i), uint(j), 1<<uint(b))
+	})
+	if found {
+		return fmt.Errorf("Theta fails (A) at %x, %x, %x", vs[i], vs[j], cl.basis[b])
+	}
+	return nil
+}
+
+// VerifyCodeCocycle checks that theta is a normalized cocycle which
+// satisfies the axioms (S), (C) and (A) from [Gri86] p. 225, ie that the loop
+// really is a code loop.
+func (cl *CL) VerifyCodeCocycle() error {
+	return cl.verifyCodeCocycle2()
+}
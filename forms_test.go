@@ -0,0 +1,191 @@
+package codeloops
+
+import (
+	"testing"
+)
+
+func TestAssocCodeMatches(t *testing.T) {
+	// Cross check the basis test against the cocycle identity on every 5
+	// element subset of the Golay basis.
+	SetCombinationsWithoutReplacement(uint(len(GolayBasis)), 5, func(s []uint) {
+		b := []uint{}
+		for _, idx := range s {
+			b = append(b, GolayBasis[idx])
+		}
+		cl, err := NewCL(CLParams{Basis: b, Random: true})
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		if cl.verifyAssoc3() != cl.verifyAssoc2() {
+			t.Fatalf("verifyAssoc3 and verifyAssoc2 disagree for %x", b)
+		}
+		if cl.verifyCommutative2() != cl.verifyCommutative() {
+			t.Fatalf("verifyCommutative2 and verifyCommutative disagree for %x", b)
+		}
+	})
+}
+
+func TestHammingCommutative(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	if cl.IsCommutative() {
+		t.Fatalf("Hamming loop is commutative.")
+	}
+	cl, err = NewCL(CLParams{Basis: golaySplit4})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	if cl.IsCommutative() != cl.verifyCommutative() {
+		t.Fatalf("IsCommutative disagrees with brute force for Golay4.")
+	}
+}
+
+func TestVerifyCodeCocycle(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		cl, err := NewCL(CLParams{Basis: HammingBasis, Random: true})
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		if err = cl.verifyCodeCocycle(); err != nil {
+			t.Fatalf("Slow check failed: %s", err)
+		}
+		if err = cl.VerifyCodeCocycle(); err != nil {
+			t.Fatalf("Fast check failed: %s", err)
+		}
+	}
+
+	cl, err := NewCL(CLParams{Basis: badHammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	if cl.verifyCodeCocycle() == nil || cl.VerifyCodeCocycle() == nil {
+		t.Fatalf("Bad Hamming basis gave a code cocycle.")
+	}
+
+	// Flip every off diagonal pair in turn. This keeps (C) happy, so any
+	// failure has to come from (A).
+	cl, err = NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	failures := 0
+	for i := uint(1); i < cl.size; i++ {
+		for j := i + 1; j < cl.size; j++ {
+			flipTheta(cl, i, j)
+			flipTheta(cl, j, i)
+			slow, fast := cl.verifyCodeCocycle(), cl.VerifyCodeCocycle()
+			if slow != nil {
+				failures++
+			}
+			if (slow == nil) != (fast == nil) {
+				t.Fatalf("Slow (%v) and fast (%v) checks disagree after flipping %d, %d", slow, fast, i, j)
+			}
+			flipTheta(cl, i, j)
+			flipTheta(cl, j, i)
+		}
+	}
+	if failures == 0 {
+		t.Fatalf("Flipping theta values never broke the cocycle.")
+	}
+}
+
+func flipTheta(cl *CL, i, j uint) {
+	cl.theta.Flip(i*cl.thetaStride*64 + j)
+}
+
+func BenchmarkVerifyCodeCocycleGolay(b *testing.B) {
+	cl, err := NewCL(CLParams{Basis: GolayBasis})
+	if err != nil {
+		b.Fatalf("Failed to create CL: %s", err)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err = cl.VerifyCodeCocycle(); err != nil {
+			b.Fatalf("%s", err)
+		}
+	}
+}
+
+func TestCheckDoublyEven(t *testing.T) {
+	for _, b := range [][]uint{HammingBasis, badHammingBasis, GolayBasis, badGolayBasis, golaySplit4} {
+		cl, err := NewCL(CLParams{Basis: b})
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		if (cl.VerifyBasis() == nil) != (cl.verifyBasis() == nil) {
+			t.Fatalf("VerifyBasis and verifyBasis disagree for %x", b)
+		}
+	}
+	// The witness is a real word with bad weight.
+	err := CheckDoublyEven(badGolayBasis)
+	de, ok := err.(*DivisibilityError)
+	if !ok {
+		t.Fatalf("Expected a DivisibilityError, got %v", err)
+	}
+	var w uint
+	for _, i := range de.Indices {
+		w ^= badGolayBasis[i]
+	}
+	if w != de.Word || BitWeight(w) != de.Weight || de.Weight%4 == 0 {
+		t.Fatalf("Bad witness %+v", de)
+	}
+	// An even weight pair meeting oddly
+	err = CheckDoublyEven([]uint{0x0f, 0x1e})
+	if de, ok = err.(*DivisibilityError); !ok || len(de.Indices) != 2 || de.Weight != 2 {
+		t.Fatalf("Expected the pair 0x0f, 0x1e as a witness, got %v", err)
+	}
+	// Too big to enumerate
+	d64, _ := DPlus(64)
+	if err = CheckDoublyEven(d64.Basis()); err != nil {
+		t.Fatalf("d64+ should be doubly even: %s", err)
+	}
+}
+
+func TestCheckTriplyEven(t *testing.T) {
+	rm14, _ := ReedMuller(1, 4)
+	if !rm14.IsTriplyEven() {
+		t.Fatalf("RM(1,4) should be triply even")
+	}
+	for _, c := range []*LinearCode{E8(), rm14} {
+		for _, b := range [][]uint{c.Basis(), c.Generator()} {
+			triply := true
+			for _, v := range VectorSpace(b) {
+				if BitWeight(v)%8 != 0 {
+					triply = false
+				}
+			}
+			if (CheckTriplyEven(b) == nil) != triply {
+				t.Fatalf("CheckTriplyEven is wrong for %x", b)
+			}
+		}
+	}
+	// Three weight 8 words meeting pairwise in 4 and all together in 1,
+	// whose sum has weight 12.
+	b := []uint{word(13, 0, 1, 2, 3, 4, 5, 6, 7), word(13, 0, 1, 2, 3, 8, 9, 10, 11), word(13, 0, 4, 5, 6, 8, 9, 10, 12)}
+	de, ok := CheckTriplyEven(b).(*DivisibilityError)
+	if !ok || len(de.Indices) != 3 || de.Weight%8 == 0 {
+		t.Fatalf("Expected a triple as witness, got %v", de)
+	}
+}
+
+func TestCocycleFlag(t *testing.T) {
+	// Only the Griess construction is trusted without a check.
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	if !cl.cocycle {
+		t.Fatalf("NewCL should mark theta as a code cocycle")
+	}
+	flipTheta(cl, 5, 9)
+	data, _ := cl.MarshalBinary()
+	bad, err := UnmarshalCL(data, false)
+	if err != nil {
+		t.Fatalf("Unverified load failed: %s", err)
+	}
+	if bad.cocycle {
+		t.Fatalf("Unverified theta marked as a code cocycle")
+	}
+}
@@ -0,0 +1,191 @@
+package codeloops
+
+import (
+	"fmt"
+	"github.com/bnagy/codeloops/bitset"
+)
+
+// Hom is a homomorphism between two code loops. It is given by the images
+// of the (positive) basis vectors of the source loop, so +b_i -> ±u_i. The
+// vectors extend linearly to a map f: C -> D between the codes, and the
+// signs extend to a sign correction s: C -> F2 so that
+//
+//	±v -> ±(-1)^s(v) f(v)
+//
+// where
+//
+//	s(x+y) = s(x) + s(y) + theta(x,y) + theta'(f(x),f(y))
+//
+// with theta' being the cocycle of the target loop. Not every choice of
+// images gives a homomorphism, so use Verify to check.
+//
+// A Hom made by NewQuotientHom instead maps into the quotient of the target
+// by {±1}, which is just its code, so signs are dropped and -1 maps to the
+// identity.
+type Hom struct {
+	src      *CL
+	dst      *CL
+	imgs     []uint // imgs[i] is the index in dst of f(v), v the i'th src vector
+	signs    *bitset.Bitset
+	quotient bool
+}
+
+// NewHom creates a map from src to dst which sends the i'th basis vector of
+// src to images[i].
+func NewHom(src, dst *CL, images []CLElem) (h *Hom, e error) {
+	if uint(len(images)) != src.basisLen {
+		e = fmt.Errorf("Need %d basis images, got %d", src.basisLen, len(images))
+		return
+	}
+	for _, img := range images {
+		if _, ok := dst.vm[img.vec]; !ok {
+			e = fmt.Errorf("Image %x is not in the target vector space", img.vec)
+			return
+		}
+		if img.sgn > 1 {
+			e = fmt.Errorf("Bad sign value %d for image %x", img.sgn, img.vec)
+			return
+		}
+	}
+	h = &Hom{src: src, dst: dst}
+	h.imgs = make([]uint, src.size)
+	h.signs = bitset.New(src.size)
+
+	// Every vector is v = vs[idx], and if j is the top bit set in idx then
+	// v = vs[idx^1<<j] + b_j, where the first vector has already been done.
+	for idx := uint(1); idx < src.size; idx++ {
+		j := uint(0)
+		for idx>>(j+1) != 0 {
+			j++
+		}
+		x, b := idx^(1<<j), uint(1)<<j
+		if x == 0 {
+			h.imgs[idx] = dst.vm[images[j].vec]
+			if images[j].sgn > 0 {
+				h.signs.Set(idx)
+			}
+			continue
+		}
+		h.imgs[idx] = dst.vm[dst.vs[h.imgs[x]]^dst.vs[h.imgs[b]]]
+		s := h.sign(x) ^ h.sign(b) ^
+			src.thetaByIdxFast(x, b) ^ dst.thetaByIdxFast(h.imgs[x], h.imgs[b])
+		if s > 0 {
+			h.signs.Set(idx)
+		}
+	}
+	return
+}
+
+// NewQuotientHom creates a map from src to dst/{±1} which sends the i'th
+// basis vector of src to images[i]. Elements of the quotient are written as
+// the positive element of their coset, and the map is ±v -> +f(v). Any
+// choice of images gives a homomorphism, since the quotient is the
+// elementary abelian group of the target code, eg the identity images give
+// the projection L -> L/{±1}.
+func NewQuotientHom(src, dst *CL, images []uint) (h *Hom, e error) {
+	signed := make([]CLElem, len(images))
+	for i, v := range images {
+		signed[i] = CLElem{sgn: Pos, vec: v}
+	}
+	if h, e = NewHom(src, dst, signed); e != nil {
+		return nil, e
+	}
+	h.quotient = true
+	return
+}
+
+func (h *Hom) sign(idx uint) uint {
+	if h.quotient {
+		return 0
+	}
+	return h.signs.Get(idx)
+}
+
+// Src returns the source loop.
+func (h *Hom) Src() *CL {
+	return h.src
+}
+
+// Dst returns the target loop.
+func (h *Hom) Dst() *CL {
+	return h.dst
+}
+
+// Sign returns the sign correction s(v) for a vector in the source code.
+func (h *Hom) Sign(vec uint) (uint, error) {
+	idx, ok := h.src.vm[vec]
+	if !ok {
+		return 0, fmt.Errorf("Vector %x not in vector space", vec)
+	}
+	return h.sign(idx), nil
+}
+
+// Apply stores the image of x in res.
+func (h *Hom) Apply(x, res *CLElem) (*CLElem, error) {
+	idx, ok := h.src.vm[x.vec]
+	if !ok {
+		return nil, fmt.Errorf("Vector %x not in vector space", x.vec)
+	}
+	res.sgn = x.sgn ^ h.sign(idx)
+	if h.quotient {
+		res.sgn = Pos
+	}
+	res.vec = h.dst.vs[h.imgs[idx]]
+	return res, nil
+}
+
+// Verify checks that the map really is a homomorphism. Since the vector
+// part is linear, this only needs the cocycle identity
+// theta(x,y) + s(x) + s(y) + s(x+y) = theta'(f(x),f(y)) for all vectors x,
+// y in the source. Maps to a quotient always pass.
+func (h *Hom) Verify() error {
+	if h.quotient {
+		return nil
+	}
+	for i := uint(0); i < h.src.size; i++ {
+		for j := uint(0); j < h.src.size; j++ {
+			lhs := h.src.thetaByIdxFast(i, j) ^ h.sign(i) ^ h.sign(j) ^ h.sign(i^j)
+			if lhs != h.dst.thetaByIdxFast(h.imgs[i], h.imgs[j]) {
+				return fmt.Errorf("Map is not a homomorphism at %x, %x", h.src.vs[i], h.src.vs[j])
+			}
+		}
+	}
+	return nil
+}
+
+// Kernel returns the elements of the source which map to +0 in the target.
+// For a map to a quotient that includes both signs of each vector.
+func (h *Hom) Kernel() (cles []CLElem) {
+	for idx, v := range h.src.vs {
+		if h.dst.vs[h.imgs[idx]] == 0 {
+			cles = append(cles, CLElem{sgn: h.sign(uint(idx)), vec: v})
+			if h.quotient {
+				cles = append(cles, CLElem{sgn: Neg, vec: v})
+			}
+		}
+	}
+	return
+}
+
+// Image returns the elements of the target which are hit by the map, with
+// the positive elements listed first. The image of -x is the negative of the
+// image of x, so both signs of every image vector appear, except for a map
+// to a quotient, where only the positive ones do.
+func (h *Hom) Image() (cles []CLElem) {
+	hit := make([]bool, h.dst.size)
+	for _, idx := range h.imgs {
+		hit[idx] = true
+	}
+	signs := []uint{Pos, Neg}
+	if h.quotient {
+		signs = signs[:1]
+	}
+	for _, sgn := range signs {
+		for idx, v := range h.dst.vs {
+			if hit[idx] {
+				cles = append(cles, CLElem{sgn: sgn, vec: v})
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,128 @@
+package codeloops
+
+import (
+	"testing"
+)
+
+func TestHomSubloop(t *testing.T) {
+	// The first 6 vectors of GolayAwesumBasis span a subcode, so the
+	// inclusion of its code loop into the Parker loop is a homomorphism, as
+	// long as we pick the right signs.
+	parker, err := NewCL(CLParams{Basis: GolayAwesumBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	sub, err := NewCL(CLParams{Basis: GolayAwesumBasis[:6], Random: true})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	images := []CLElem{}
+	for _, b := range sub.basis {
+		images = append(images, CLElem{sgn: Pos, vec: b})
+	}
+	h, err := NewHom(sub, parker, images)
+	if err != nil {
+		t.Fatalf("Failed to create Hom: %s", err)
+	}
+	if err = h.Verify(); err != nil {
+		t.Fatalf("Inclusion is not a homomorphism: %s", err)
+	}
+	if len(h.Kernel()) != 1 {
+		t.Fatalf("Expected trivial kernel, got %d elements", len(h.Kernel()))
+	}
+	if len(h.Image()) != 2*sub.Size() {
+		t.Fatalf("Expected image of size %d, got %d", 2*sub.Size(), len(h.Image()))
+	}
+}
+
+func TestHomKernel(t *testing.T) {
+	// Kill the last basis vector of an associative subloop. Since the target
+	// is a group with trivial cocycle, the quotient map is a homomorphism
+	// with kernel {±0, ±b}.
+	src, err := NewCL(CLParams{Basis: golaySplit4})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	dst, err := NewCL(CLParams{Basis: golaySplit4[:3]})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	images := []CLElem{}
+	for _, b := range dst.basis {
+		images = append(images, CLElem{sgn: Pos, vec: b})
+	}
+	images = append(images, CLElem{sgn: Pos, vec: 0})
+	h, err := NewHom(src, dst, images)
+	if err != nil {
+		t.Fatalf("Failed to create Hom: %s", err)
+	}
+	if err = h.Verify(); err != nil {
+		t.Fatalf("Quotient map is not a homomorphism: %s", err)
+	}
+	if len(h.Kernel()) != 2 {
+		t.Fatalf("Expected kernel of size 2, got %d", len(h.Kernel()))
+	}
+	if len(h.Image()) != 2*dst.Size() {
+		t.Fatalf("Expected map to be onto, got image of size %d", len(h.Image()))
+	}
+	x := new(CLElem)
+	for _, k := range h.Kernel() {
+		h.Apply(&k, x)
+		if x.sgn != Pos || x.vec != 0 {
+			t.Fatalf("Kernel element %s maps to %s", &k, x)
+		}
+	}
+}
+
+func TestHomNotHom(t *testing.T) {
+	// The Hamming loop isn't associative, so it can't embed in a group.
+	src, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	dst, err := NewCL(CLParams{Basis: golaySplit4})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	images := []CLElem{}
+	for _, b := range dst.basis {
+		images = append(images, CLElem{sgn: Pos, vec: b})
+	}
+	h, err := NewHom(src, dst, images)
+	if err != nil {
+		t.Fatalf("Failed to create Hom: %s", err)
+	}
+	if err = h.Verify(); err == nil {
+		t.Fatalf("Embedded the Hamming loop in a group.")
+	}
+}
+
+func TestHomQuotient(t *testing.T) {
+	// The projection of the Hamming loop onto its code kills -1.
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	h, err := NewQuotientHom(cl, cl, cl.basis)
+	if err != nil {
+		t.Fatalf("Failed to create Hom: %s", err)
+	}
+	if err = h.Verify(); err != nil {
+		t.Fatalf("Projection is not a homomorphism: %s", err)
+	}
+	if len(h.Kernel()) != 2 || len(h.Image()) != cl.Size() {
+		t.Fatalf("Expected kernel {±0} and image of size %d, got %d and %d", cl.Size(), len(h.Kernel()), len(h.Image()))
+	}
+	x, y := new(CLElem), new(CLElem)
+	for _, e := range cl.LoopElems() {
+		h.Apply(&e, x)
+		if x.sgn != Pos || x.vec != e.vec {
+			t.Fatalf("%s projects to %s", &e, x)
+		}
+	}
+	// -1 is in the kernel.
+	h.Apply(&CLElem{sgn: Neg, vec: 0}, y)
+	if y.sgn != Pos || y.vec != 0 {
+		t.Fatalf("-1 should map to the identity, got %s", y)
+	}
+}
@@ -0,0 +1,94 @@
+package codeloops
+
+// In a code loop the associator of ±x, ±y, ±z is (-1)^|x&y&z| and the
+// commutator of ±x, ±y is (-1)^(|x&y|/2) ([Gri86] p. 225), independent of
+// the signs, so both signs of a vector are in the nucleus (or center)
+// together. For a code cocycle this lets us find them from the basis. The
+// associator is trilinear, so ±a is in the nucleus iff |a&bi&bj| is even
+// for all basis pairs, and then the commutator is linear in the other
+// argument, so ±a is central iff also |a&bi|/2 is even for all i.
+
+// assoc returns the associator bit for the vectors with indices i, j, k,
+// computed from theta.
+func (cl *CL) assoc(i, j, k uint) uint {
+	return cl.thetaByIdxFast(i, j) ^ cl.thetaByIdxFast(i^j, k) ^
+		cl.thetaByIdxFast(j, k) ^ cl.thetaByIdxFast(i, j^k)
+}
+
+// inNucleus reports whether ±vs[a] associate with everything.
+func (cl *CL) inNucleus(a uint) bool {
+	if cl.cocycle {
+		v := cl.vs[a]
+		for i := 0; i < len(cl.basis); i++ {
+			for j := i + 1; j < len(cl.basis); j++ {
+				if BitWeight(v&cl.basis[i]&cl.basis[j])%2 != 0 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for x := uint(0); x < cl.size; x++ {
+		for y := uint(0); y < cl.size; y++ {
+			if cl.assoc(a, x, y)|cl.assoc(x, a, y)|cl.assoc(x, y, a) != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// inCenter reports whether ±vs[a] are in the nucleus and commute with
+// everything.
+func (cl *CL) inCenter(a uint) bool {
+	if !cl.inNucleus(a) {
+		return false
+	}
+	if cl.cocycle {
+		for _, b := range cl.basis {
+			if (BitWeight(cl.vs[a]&b)/2)%2 != 0 {
+				return false
+			}
+		}
+		return true
+	}
+	for x := uint(0); x < cl.size; x++ {
+		if cl.thetaByIdxFast(a, x) != cl.thetaByIdxFast(x, a) {
+			return false
+		}
+	}
+	return true
+}
+
+func (cl *CL) elemsWhere(keep func(idx uint) bool) (cles []CLElem) {
+	vecs := []uint{}
+	for idx, v := range cl.vs {
+		if keep(uint(idx)) {
+			vecs = append(vecs, v)
+		}
+	}
+	for _, sgn := range []uint{Pos, Neg} {
+		for _, v := range vecs {
+			cles = append(cles, CLElem{sgn: sgn, vec: v})
+		}
+	}
+	return
+}
+
+// Nucleus returns the elements which associate with every pair of
+// elements, with the positive elements listed first.
+func (cl *CL) Nucleus() []CLElem {
+	return cl.elemsWhere(cl.inNucleus)
+}
+
+// Center returns the elements in the nucleus which commute with every
+// element, with the positive elements listed first. It always contains ±0.
+func (cl *CL) Center() []CLElem {
+	return cl.elemsWhere(cl.inCenter)
+}
+
+// WeightDistribution returns the weight distribution of the code, as
+// LinearCode.WeightDistribution does, up to the code length.
+func (cl *CL) WeightDistribution() []uint {
+	return weightDistribution(cl.basis, CodeLength(cl.basis))
+}
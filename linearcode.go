@@ -0,0 +1,185 @@
+package codeloops
+
+import (
+	"fmt"
+	"math/bits"
+)
+
+// LinearCode is a binary linear code of length n, given by a basis. Words
+// are uints, and coordinates are read most significant bit first, as in
+// ReadBasis, so coordinate 0 is bit n-1.
+type LinearCode struct {
+	n      uint
+	basis  []uint // as given
+	gen    []uint // reduced row echelon form
+	pivots []uint // pivot column of each row of gen
+}
+
+// NewLinearCode returns the code spanned by basis, which must be linearly
+// independent and fit in length n. Zero n means CodeLength(basis).
+func NewLinearCode(basis []uint, n uint) (*LinearCode, error) {
+	if n == 0 {
+		n = CodeLength(basis)
+	}
+	if n == 0 || n > uintBits {
+		return nil, fmt.Errorf("Bad code length %d", n)
+	}
+	if CodeLength(basis) > n {
+		return nil, fmt.Errorf("Basis doesn't fit in code length %d", n)
+	}
+	if i := dependentVector(basis); i >= 0 {
+		return nil, fmt.Errorf("Basis vector %d (0x%x) depends on the ones before it", i, basis[i])
+	}
+	c := &LinearCode{n: n, basis: append([]uint{}, basis...)}
+	c.gen, c.pivots = rowReduce(basis, n)
+	return c, nil
+}
+
+// Length returns the length of the code.
+func (c *LinearCode) Length() uint {
+	return c.n
+}
+
+// Dimension returns the dimension of the code.
+func (c *LinearCode) Dimension() uint {
+	return uint(len(c.gen))
+}
+
+// Basis returns the basis the code was made from.
+func (c *LinearCode) Basis() []uint {
+	return append([]uint{}, c.basis...)
+}
+
+// Generator returns the generator matrix in reduced row echelon form.
+func (c *LinearCode) Generator() []uint {
+	return append([]uint{}, c.gen...)
+}
+
+func (c *LinearCode) col(i uint) uint {
+	return 1 << (c.n - 1 - i)
+}
+
+// ParityCheck returns a parity check matrix H, whose rows are a basis of
+// the dual code, so that v is in the code exactly when v has even overlap
+// with every row of H. For each non-pivot column j of the generator there
+// is one row: e_j plus the pivot columns of the generator rows which have
+// a 1 in column j.
+func (c *LinearCode) ParityCheck() (h []uint) {
+	isPivot := map[uint]bool{}
+	for _, p := range c.pivots {
+		isPivot[p] = true
+	}
+	for j := uint(0); j < c.n; j++ {
+		if isPivot[j] {
+			continue
+		}
+		row := c.col(j)
+		for i, g := range c.gen {
+			if g&c.col(j) != 0 {
+				row |= c.col(c.pivots[i])
+			}
+		}
+		h = append(h, row)
+	}
+	return
+}
+
+// Contains reports whether v is a code word.
+func (c *LinearCode) Contains(v uint) bool {
+	if c.n < uintBits && v>>c.n != 0 {
+		return false
+	}
+	for i, g := range c.gen {
+		if v&c.col(c.pivots[i]) != 0 {
+			v ^= g
+		}
+	}
+	return v == 0
+}
+
+// Words returns all the code words, in VectorSpace order for the basis.
+func (c *LinearCode) Words() []uint {
+	return VectorSpace(c.basis)
+}
+
+// WeightDistribution returns the number of code words of each weight, so
+// that wd[w] is the number of words of weight w, for w up to the length.
+func (c *LinearCode) WeightDistribution() []uint {
+	return weightDistribution(c.gen, c.n)
+}
+
+// weightDistribution counts the words of each weight up to n in the span of
+// an independent basis. The words are visited in Gray code order, so each
+// step is one XOR.
+func weightDistribution(basis []uint, n uint) []uint {
+	wd := make([]uint, n+1)
+	wd[0] = 1
+	v := uint(0)
+	for i := uint(1); i < 1<<uint(len(basis)); i++ {
+		v ^= basis[bits.TrailingZeros(i)]
+		wd[BitWeight(v)]++
+	}
+	return wd
+}
+
+// MinimumDistance returns the smallest weight of a non-zero word, or 0 for
+// the zero code.
+func (c *LinearCode) MinimumDistance() uint {
+	for w, count := range c.WeightDistribution() {
+		if w > 0 && count > 0 {
+			return uint(w)
+		}
+	}
+	return 0
+}
+
+// Dual returns the dual code, of dimension Length - Dimension. The dual of
+// the whole space is the zero code, which has no basis, so that's an error.
+func (c *LinearCode) Dual() (*LinearCode, error) {
+	h := c.ParityCheck()
+	if len(h) == 0 {
+		return nil, fmt.Errorf("The dual of the whole space is the zero code")
+	}
+	return NewLinearCode(h, c.n)
+}
+
+// IsSelfOrthogonal reports whether the code is contained in its dual, ie
+// every pair of words has even overlap.
+func (c *LinearCode) IsSelfOrthogonal() bool {
+	for i, x := range c.gen {
+		for _, y := range c.gen[i:] {
+			if BitWeight(x&y)%2 != 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// IsDoublyEven reports whether every word has weight divisible by 4. Since
+// wt(x+y) = wt(x) + wt(y) - 2|x&y|, it's enough that the basis vectors do
+// and the code is self orthogonal, see CheckDoublyEven.
+func (c *LinearCode) IsDoublyEven() bool {
+	return CheckDoublyEven(c.gen) == nil
+}
+
+// IsTriplyEven reports whether every word has weight divisible by 8, see
+// CheckTriplyEven.
+func (c *LinearCode) IsTriplyEven() bool {
+	return CheckTriplyEven(c.gen) == nil
+}
+
+// IsSelfDual reports whether the code is its own dual.
+func (c *LinearCode) IsSelfDual() bool {
+	return 2*c.Dimension() == c.n && c.IsSelfOrthogonal()
+}
+
+// NewCL returns the code loop of the code, built from the basis it was
+// made with, so any V/W split in the basis is kept. The code must be
+// doubly even.
+func (c *LinearCode) NewCL(random bool, seed int64) (*CL, error) {
+	if !c.IsDoublyEven() {
+		return nil, fmt.Errorf("Code is not doubly even")
+	}
+	return NewCL(CLParams{Basis: c.Basis(), Random: random, Seed: seed})
+}
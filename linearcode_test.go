@@ -0,0 +1,80 @@
+package codeloops
+
+import (
+	"testing"
+)
+
+func TestLinearCodeGolay(t *testing.T) {
+	c, err := NewLinearCode(GolayBasis, 24)
+	if err != nil {
+		t.Fatalf("Failed to create code: %s", err)
+	}
+	if c.Length() != 24 || c.Dimension() != 12 || c.MinimumDistance() != 8 {
+		t.Fatalf("Expected a [24,12,8] code, got [%d,%d,%d]", c.Length(), c.Dimension(), c.MinimumDistance())
+	}
+	wd := c.WeightDistribution()
+	want := map[int]uint{0: 1, 8: 759, 12: 2576, 16: 759, 24: 1}
+	for w, n := range wd {
+		if n != want[w] {
+			t.Fatalf("Expected %d words of weight %d, got %d", want[w], w, n)
+		}
+	}
+	if !c.IsDoublyEven() || !c.IsSelfDual() {
+		t.Fatalf("Golay code should be doubly even and self dual")
+	}
+	for _, h := range c.ParityCheck() {
+		if !c.Contains(h) {
+			t.Fatalf("Self dual code should contain its parity checks")
+		}
+	}
+	for _, v := range GolayBasis {
+		if !c.Contains(v ^ GolayBasis[0]) {
+			t.Fatalf("Code should contain 0x%x", v^GolayBasis[0])
+		}
+	}
+	if c.Contains(1) || c.Contains(1<<24) {
+		t.Fatalf("Code shouldn't contain weight 1 words")
+	}
+	cl, err := c.NewCL(false, 0)
+	if err != nil || cl.Size() != 1<<12 {
+		t.Fatalf("Failed to build the Parker loop: %v", err)
+	}
+}
+
+func TestLinearCodeDual(t *testing.T) {
+	// The [7,4,3] Hamming code, as in ReadBasis: coordinate 0 first.
+	c, err := NewLinearCode([]uint{0x46, 0x25, 0x13, 0x0f}, 7)
+	if err != nil {
+		t.Fatalf("Failed to create code: %s", err)
+	}
+	if c.MinimumDistance() != 3 || c.IsDoublyEven() || c.IsSelfOrthogonal() {
+		t.Fatalf("Expected a [7,4,3] code which isn't self orthogonal")
+	}
+	// The dual is the [7,3,4] simplex code, all of whose words have weight 4
+	d, err := c.Dual()
+	if err != nil {
+		t.Fatalf("Failed to make dual: %s", err)
+	}
+	if d.Dimension() != 3 || d.MinimumDistance() != 4 || d.WeightDistribution()[4] != 7 {
+		t.Fatalf("Expected the simplex code, got weights %v", d.WeightDistribution())
+	}
+	for _, x := range c.Words() {
+		for _, y := range d.Words() {
+			if BitWeight(x&y)%2 != 0 {
+				t.Fatalf("0x%x and 0x%x aren't orthogonal", x, y)
+			}
+		}
+	}
+	// Generator is in reduced row echelon form
+	for i, g := range c.Generator() {
+		if g>>uint(6-i)&1 != 1 {
+			t.Fatalf("Generator row %d is %07b", i, g)
+		}
+	}
+	if _, err = c.NewCL(false, 0); err == nil {
+		t.Fatalf("Expected an error building a loop from a code which isn't doubly even")
+	}
+	if _, err = NewLinearCode([]uint{3, 5, 6}, 3); err == nil {
+		t.Fatalf("Expected an error for a dependent basis")
+	}
+}
@@ -0,0 +1,224 @@
+package codeloops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc64"
+	"io"
+
+	"github.com/bnagy/codeloops/bitset"
+)
+
+// The binary encoding of a CL is (all little endian)
+//
+//	magic      [8]byte "CODELOOP"
+//	version    uint32
+//	flags      uint32  bit 0: random choices, bit 1: theta present
+//	seed       int64   CLParams.Seed
+//	basisLen   uint32
+//	vLen       uint32  number of basis vectors spanning V in the alpha split
+//	basis      [basisLen]uint64
+//	pathLen    uint32
+//	path       [pathLen]byte  the Seed string recording the choices made
+//	theta      bitset (see bitset.MarshalBinary), if present
+//	alpha      bitset
+//	checksum   uint64  CRC-64 (ECMA) of everything before it
+//
+// Loops built with NewCLFromAlpha have no theta table, so it is left out.
+
+const (
+	clMagic   = "CODELOOP"
+	clVersion = 1
+
+	clFlagRandom = 1 << 0
+	clFlagTheta  = 1 << 1
+)
+
+// MarshalBinary encodes the loop, including the complete theta table, so it
+// can be loaded again without rebuilding it.
+func (cl *CL) MarshalBinary() ([]byte, error) {
+	if cl.closed {
+		return nil, fmt.Errorf("Can't encode a closed loop")
+	}
+	var buf bytes.Buffer
+	le := binary.LittleEndian
+
+	var flags uint32
+	if cl.params.Random {
+		flags |= clFlagRandom
+	}
+	if cl.theta != nil {
+		flags |= clFlagTheta
+	}
+	buf.WriteString(clMagic)
+	binary.Write(&buf, le, uint32(clVersion))
+	binary.Write(&buf, le, flags)
+	binary.Write(&buf, le, cl.params.Seed)
+	binary.Write(&buf, le, uint32(cl.basisLen))
+	binary.Write(&buf, le, uint32(len(cl.basis)/2))
+	for _, b := range cl.basis {
+		binary.Write(&buf, le, uint64(b))
+	}
+	binary.Write(&buf, le, uint32(len(cl.Seed)))
+	buf.WriteString(cl.Seed)
+
+	sets := []*bitset.Bitset{cl.alpha}
+	if cl.theta != nil {
+		sets = []*bitset.Bitset{cl.theta, cl.alpha}
+	}
+	for _, bs := range sets {
+		data, e := bs.MarshalBinary()
+		if e != nil {
+			return nil, e
+		}
+		buf.Write(data)
+	}
+	binary.Write(&buf, le, crc64.Checksum(buf.Bytes(), crcTable))
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a loop written by MarshalBinary, and checks that
+// theta really is a code cocycle. Use UnmarshalCL to skip the check.
+func (cl *CL) UnmarshalBinary(data []byte) error {
+	loaded, e := UnmarshalCL(data, true)
+	if e != nil {
+		return e
+	}
+	*cl = *loaded
+	return nil
+}
+
+// UnmarshalCL decodes a loop written by MarshalBinary. The header, sizes
+// and checksum are always validated. If verify is set, the cocycle axioms
+// are checked too (see VerifyCodeCocycle), which is O(k*N^2). Only a
+// verified loop answers IsAssoc and friends from the basis.
+func UnmarshalCL(data []byte, verify bool) (cl *CL, e error) {
+	le := binary.LittleEndian
+	if len(data) < len(clMagic)+8 || string(data[:len(clMagic)]) != clMagic {
+		return nil, fmt.Errorf("Not an encoded code loop")
+	}
+	body, sum := data[:len(data)-8], le.Uint64(data[len(data)-8:])
+	if crc64.Checksum(body, crcTable) != sum {
+		return nil, fmt.Errorf("Code loop checksum mismatch")
+	}
+
+	r := bytes.NewReader(body[len(clMagic):])
+	var hdr struct {
+		Version  uint32
+		Flags    uint32
+		Seed     int64
+		BasisLen uint32
+		VLen     uint32
+	}
+	if e = binary.Read(r, le, &hdr); e != nil {
+		return nil, fmt.Errorf("Reading code loop header: %s", e)
+	}
+	if hdr.Version != clVersion {
+		return nil, fmt.Errorf("Unsupported code loop version %d", hdr.Version)
+	}
+	if hdr.BasisLen == 0 || hdr.BasisLen > maxStoredBasisLen {
+		return nil, fmt.Errorf("Bad basis length %d", hdr.BasisLen)
+	}
+	if hdr.VLen != hdr.BasisLen/2 {
+		return nil, fmt.Errorf("Unsupported alpha split %d+%d", hdr.VLen, hdr.BasisLen-hdr.VLen)
+	}
+
+	basis := make([]uint64, hdr.BasisLen)
+	if e = binary.Read(r, le, basis); e != nil {
+		return nil, fmt.Errorf("Reading basis: %s", e)
+	}
+	var pathLen uint32
+	if e = binary.Read(r, le, &pathLen); e != nil || uint64(pathLen) > uint64(r.Len()) {
+		return nil, fmt.Errorf("Reading seed path: bad length")
+	}
+	path := make([]byte, pathLen)
+	io.ReadFull(r, path)
+
+	b := make([]uint, hdr.BasisLen)
+	for i, v := range basis {
+		b[i] = uint(v)
+	}
+	if i := dependentVector(b); i >= 0 {
+		return nil, fmt.Errorf("Basis vector %d (0x%x) depends on the ones before it", i, b[i])
+	}
+	// The table sizes depend only on the basis length, so make sure they
+	// are all there before allocating anything for them.
+	k := uint(hdr.BasisLen)
+	side := uint64(1)<<(k/2) + uint64(1)<<(k-k/2)
+	want := bitsetBytes(side * side)
+	if hdr.Flags&clFlagTheta != 0 {
+		want += bitsetBytes(64 * thetaWords(k))
+	}
+	if uint64(r.Len()) != want {
+		return nil, fmt.Errorf("Code loop tables are %d bytes, expected %d", r.Len(), want)
+	}
+
+	cl = new(CL)
+	cl.basis = b
+	cl.basisLen = k
+	cl.size = 1 << cl.basisLen
+	cl.params = CLParams{Basis: cl.basis, Random: hdr.Flags&clFlagRandom != 0, Seed: hdr.Seed}
+	cl.Seed = string(path)
+	cl.initSpace()
+	cl.initAlpha()
+
+	if hdr.Flags&clFlagTheta != 0 {
+		if cl.theta, e = readBitset(r, cl.size*cl.thetaStride*64); e != nil {
+			return nil, fmt.Errorf("Reading theta: %s", e)
+		}
+	}
+	if cl.alpha, e = readBitset(r, cl.alphaSz*cl.alphaSz); e != nil {
+		return nil, fmt.Errorf("Reading alpha: %s", e)
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("%d bytes of trailing data", r.Len())
+	}
+
+	// alpha is just part of theta, so they had better agree.
+	if cl.theta != nil {
+		for _, x := range cl.vsAlpha {
+			for _, y := range cl.vsAlpha {
+				if cl.alphaByVecFast(x, y) != cl.thetaByVecFast(x, y) {
+					return nil, fmt.Errorf("Alpha doesn't match theta at %x, %x", x, y)
+				}
+			}
+		}
+	}
+	if verify {
+		if e = cl.VerifyCodeCocycle(); e != nil {
+			return nil, e
+		}
+		cl.cocycle = true
+	}
+	return
+}
+
+// bitsetBytes is the length of an encoded bitset of n bits.
+func bitsetBytes(n uint64) uint64 {
+	return 8 + 8*((n+63)/64)
+}
+
+// readBitset reads one encoded bitset of n bits from r.
+func readBitset(r *bytes.Reader, n uint) (*bitset.Bitset, error) {
+	var got uint64
+	if e := binary.Read(r, binary.LittleEndian, &got); e != nil {
+		return nil, e
+	}
+	if got != uint64(n) {
+		return nil, fmt.Errorf("Expected %d bits, got %d", n, got)
+	}
+	if uint64(r.Len()) < bitsetBytes(got)-8 {
+		return nil, fmt.Errorf("Only %d bytes left for %d bits", r.Len(), got)
+	}
+	data := make([]byte, 8+8*((n+63)/64))
+	binary.LittleEndian.PutUint64(data, got)
+	if _, e := io.ReadFull(r, data[8:]); e != nil {
+		return nil, e
+	}
+	bs := new(bitset.Bitset)
+	if e := bs.UnmarshalBinary(data); e != nil {
+		return nil, e
+	}
+	return bs, nil
+}
@@ -0,0 +1,125 @@
+package codeloops
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc64"
+	"path/filepath"
+	"testing"
+)
+
+func TestMarshalGolay(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: GolayBasis, Random: true, Seed: 7})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	data, err := cl.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Failed to marshal: %s", err)
+	}
+	cl2 := new(CL)
+	if err = cl2.UnmarshalBinary(data); err != nil {
+		t.Fatalf("Failed to unmarshal: %s", err)
+	}
+	if !cl.EqualTheta(cl2) || !cl.alpha.Equal(cl2.alpha) {
+		t.Fatalf("Tables differ after round trip")
+	}
+	if cl2.Seed != cl.Seed || cl2.params.Seed != 7 || !cl2.params.Random {
+		t.Fatalf("Params differ after round trip: %+v", cl2.params)
+	}
+	data2, _ := cl2.MarshalBinary()
+	if !bytes.Equal(data, data2) {
+		t.Fatalf("Encoding isn't stable")
+	}
+}
+
+func TestMarshalBad(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	data, _ := cl.MarshalBinary()
+
+	bad := append([]byte{}, data...)
+	bad[len(bad)/2] ^= 1
+	if _, err = UnmarshalCL(bad, false); err == nil {
+		t.Fatalf("Corrupt data should fail the checksum")
+	}
+
+	// Break theta(5,9) but fix up the checksum. Only the cocycle check can
+	// catch that.
+	flipTheta(cl, 5, 9)
+	bad, _ = cl.MarshalBinary()
+	loaded, err := UnmarshalCL(bad, false)
+	if err != nil {
+		t.Fatalf("Unverified load failed: %s", err)
+	}
+	if loaded.cocycle {
+		t.Fatalf("Unverified load marked as a code cocycle")
+	}
+	if loaded, err = UnmarshalCL(data, true); err != nil || !loaded.cocycle {
+		t.Fatalf("Verified load should be marked as a code cocycle: %v", err)
+	}
+	if _, err = UnmarshalCL(bad, true); err == nil {
+		t.Fatalf("Verified load should reject a broken cocycle")
+	}
+
+	bad = append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(bad[8:], 99)
+	binary.LittleEndian.PutUint64(bad[len(bad)-8:], crc64.Checksum(bad[:len(bad)-8], crcTable))
+	if _, err = UnmarshalCL(bad, false); err == nil {
+		t.Fatalf("Unknown version should be rejected")
+	}
+
+	// A header claiming a huge basis, or a dependent one, must be rejected
+	// before anything is allocated for it, even with a good checksum.
+	for _, fix := range []func([]byte){
+		func(b []byte) { binary.LittleEndian.PutUint32(b[24:], 24) },
+		func(b []byte) { copy(b[40:48], b[32:40]) },
+	} {
+		bad = append([]byte{}, data...)
+		fix(bad)
+		binary.LittleEndian.PutUint64(bad[len(bad)-8:], crc64.Checksum(bad[:len(bad)-8], crcTable))
+		if _, err = UnmarshalCL(bad, false); err == nil {
+			t.Fatalf("Bad header should be rejected")
+		}
+	}
+}
+
+func TestMarshalAlphaOnly(t *testing.T) {
+	full, err := NewCL(CLParams{Basis: GolayAwesumBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	cl, err := NewCLFromAlpha(GolayAwesumBasis, full.RestrictedTheta())
+	if err != nil {
+		t.Fatalf("Failed to create CL from alpha: %s", err)
+	}
+	data, _ := cl.MarshalBinary()
+	cl2, err := UnmarshalCL(data, false)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal: %s", err)
+	}
+	if cl2.theta != nil || !cl2.alpha.Equal(full.alpha) {
+		t.Fatalf("Alpha-only loop didn't round trip")
+	}
+}
+
+func TestMarshalClosed(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "hamming.theta")
+	if err = cl.WriteThetaFile(path); err != nil {
+		t.Fatalf("Failed to write theta file: %s", err)
+	}
+	opened, err := OpenCL(path)
+	if err != nil {
+		t.Fatalf("Failed to open theta file: %s", err)
+	}
+	opened.Close()
+	if _, err = opened.MarshalBinary(); err == nil {
+		t.Fatalf("Encoding a closed loop should fail")
+	}
+}
@@ -0,0 +1,28 @@
+//go:build unix
+// +build unix
+
+package codeloops
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// mapWords maps n words of f starting at off (which must be a multiple of
+// 8) read-only. On big endian hosts the words are read into memory instead.
+func mapWords(f *os.File, off int64, n int) (words []uint64, unmap func() error, e error) {
+	if !hostLittleEndian || n == 0 {
+		words, e = readWords(f, off, n)
+		return words, func() error { return nil }, e
+	}
+	// mmap needs a page aligned offset, so map from the start of the file
+	// and skip the header.
+	data, e := syscall.Mmap(int(f.Fd()), 0, int(off)+8*n, syscall.PROT_READ, syscall.MAP_SHARED)
+	if e != nil {
+		return nil, nil, fmt.Errorf("Mapping theta table: %s", e)
+	}
+	words = unsafe.Slice((*uint64)(unsafe.Pointer(&data[off])), n)
+	return words, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,60 @@
+package codeloops
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// firstTriple searches all triples (i, j, k) in [0,ni) x [0,nj) x [0,nk)
+// for one where bad returns true. The work is split by i across goroutines, and the result is
+// the lexicographically first bad triple, so callers get exactly the same
+// witness as they would from the obvious triple loop. Workers give up on a
+// row as soon as a bad triple has been found in an earlier row.
+func firstTriple(ni, nj, nk int, bad func(i, j, k int) bool) (wi, wj, wk int, found bool) {
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > ni {
+		workers = ni
+	}
+
+	var next int64 = -1
+	// best is the earliest row known to contain a bad triple
+	best := int64(ni)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := atomic.AddInt64(&next, 1)
+				if i >= int64(ni) || i > atomic.LoadInt64(&best) {
+					return
+				}
+			row:
+				for j := 0; j < nj; j++ {
+					// rows are claimed in order, so if someone else has
+					// already found an earlier bad row, this one is moot.
+					if atomic.LoadInt64(&best) < i {
+						return
+					}
+					for k := 0; k < nk; k++ {
+						if bad(int(i), j, k) {
+							mu.Lock()
+							if i < best {
+								wi, wj, wk, found = int(i), j, k, true
+								atomic.StoreInt64(&best, i)
+							}
+							mu.Unlock()
+							break row
+						}
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return
+}
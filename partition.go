@@ -0,0 +1,198 @@
+package codeloops
+
+import (
+	"fmt"
+)
+
+// The alpha machinery splits a basis of length k into V (the first k/2
+// vectors) and W (the rest), and it is nicest when the code loops of V and
+// W are themselves well behaved, eg the special Golay basis has V and W
+// spanning associative codes with every weight divisible by 8.
+// PartitionBasis searches for such splits.
+
+// BasisPredicate decides whether a set of vectors is acceptable as (part of)
+// a block. The search calls it on every prefix of a block as it grows, and
+// prunes when it fails, so it should be hereditary: if it holds for a set it
+// should hold for every subset. IsAssocCode and IsCommutativeCode are
+// BasisPredicates, as is anything from WeightsDivisibleBy.
+type BasisPredicate func(b []uint) bool
+
+// WeightsDivisibleBy returns a predicate which holds when every word in the
+// span has weight divisible by m. For m = 2, 4 and 8 this is decided from
+// the basis (see CheckDoublyEven and CheckTriplyEven), otherwise the span is
+// enumerated. m = 0 is treated like 1, so the predicate always holds.
+func WeightsDivisibleBy(m uint) BasisPredicate {
+	return func(b []uint) bool {
+		switch m {
+		case 0, 1:
+			return true
+		case 2:
+			for _, v := range b {
+				if BitWeight(v)%2 != 0 {
+					return false
+				}
+			}
+			return true
+		case 4:
+			return CheckDoublyEven(b) == nil
+		case 8:
+			return CheckTriplyEven(b) == nil
+		}
+		for _, v := range VectorSpace(b) {
+			if BitWeight(v)%m != 0 {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// PartitionParams describes a search for a split of a code's basis.
+type PartitionParams struct {
+	Basis      []uint           // any basis of the code
+	VDim, WDim int              // dimensions of the V and W blocks
+	V, W       []BasisPredicate // every predicate must hold for its block
+	All        bool             // find every solution, not just the first
+	// Limit is the most blocks the search will try before giving up, see
+	// PartitionBasis. Zero means DefaultPartitionLimit, negative no limit.
+	Limit int
+}
+
+// DefaultPartitionLimit is the Limit used when none is given. A block
+// costs a few microseconds to try, so the search gives up within seconds.
+const DefaultPartitionLimit = 1 << 20
+
+// Partition is a split of a code's basis into V and W blocks, plus Rest,
+// which completes them to a basis of the whole code.
+type Partition struct {
+	V, W, Rest []uint
+}
+
+// Basis returns the complete basis V, W, Rest. When VDim is half the
+// dimension of the code, V is the first half of the basis, so NewCL will
+// use it as the V of the alpha square, and W plus Rest as the W.
+func (p *Partition) Basis() []uint {
+	b := append([]uint{}, p.V...)
+	b = append(b, p.W...)
+	return append(b, p.Rest...)
+}
+
+// PartitionBasis searches the words of the code spanned by p.Basis for an
+// independent V of dimension p.VDim and W of dimension p.WDim, with V and W
+// spanning subspaces which meet only in 0, and which satisfy the
+// predicates. Rest is then filled in greedily from p.Basis.
+//
+// Blocks are built from the words in VectorSpace order, so the first
+// solution is always the same. The candidate words are the whole code,
+// which is 2^k words for a basis of length k, and the search is a
+// backtracking one through blocks of them, which is exponential in the
+// block dimensions and is slowest when there is no solution. So it tries at
+// most p.Limit blocks, and it is an error to run out. With p.All every
+// solution is returned, but each pair of spans (V, W) only once, with the
+// first bases found for them. Since there are far more subspaces than
+// words, that is only practical for small codes. It is an error if there is
+// no solution.
+func PartitionBasis(p PartitionParams) (parts []Partition, e error) {
+	if i := dependentVector(p.Basis); i >= 0 {
+		return nil, fmt.Errorf("Basis vector %d (0x%x) depends on the ones before it", i, p.Basis[i])
+	}
+	if p.VDim < 0 || p.WDim < 0 || p.VDim+p.WDim > len(p.Basis) {
+		return nil, fmt.Errorf("Can't split a basis of length %d into blocks of %d and %d", len(p.Basis), p.VDim, p.WDim)
+	}
+	words := VectorSpace(p.Basis)[1:]
+	vCands, wCands := candidates(words, p.V), candidates(words, p.W)
+	budget := p.Limit
+	switch {
+	case budget == 0:
+		budget = DefaultPartitionLimit
+	case budget < 0:
+		budget = int(^uint(0) >> 1)
+	}
+	limit := budget
+
+	seenV := map[string]bool{}
+	extendBlock(nil, vCands, 0, p.VDim, &budget, func(v []uint) bool {
+		return dependentVector(v) < 0 && allHold(p.V, v)
+	}, func(v []uint) bool {
+		if seenV[spanKey(v)] {
+			return false
+		}
+		seenV[spanKey(v)] = true
+		seenW := map[string]bool{}
+		return extendBlock(nil, wCands, 0, p.WDim, &budget, func(w []uint) bool {
+			return dependentVector(append(append([]uint{}, v...), w...)) < 0 && allHold(p.W, w)
+		}, func(w []uint) bool {
+			if seenW[spanKey(w)] {
+				return false
+			}
+			seenW[spanKey(w)] = true
+			vw := append(append([]uint{}, v...), w...)
+			all := independentSubset(append(vw, p.Basis...))
+			parts = append(parts, Partition{
+				V:    append([]uint{}, v...),
+				W:    append([]uint{}, w...),
+				Rest: all[len(vw):],
+			})
+			return !p.All
+		})
+	})
+	if budget <= 0 && (p.All || len(parts) == 0) {
+		return nil, fmt.Errorf("Gave up after trying %d blocks, raise the Limit to search further", limit)
+	}
+	if len(parts) == 0 {
+		return nil, fmt.Errorf("No V of dimension %d and W of dimension %d satisfy the predicates", p.VDim, p.WDim)
+	}
+	return
+}
+
+// extendBlock tries to extend block by n more of cands, taken in order
+// from index from, so that every prefix passes good. found is called on
+// each complete block, and returns true to stop the search, which
+// extendBlock then reports. Each block tried uses up one of budget, and the
+// search also stops when it runs out.
+func extendBlock(block, cands []uint, from, n int, budget *int, good, found func([]uint) bool) bool {
+	if n == 0 {
+		return found(block)
+	}
+	for i := from; i < len(cands); i++ {
+		if *budget <= 0 {
+			return true
+		}
+		*budget--
+		next := append(block[:len(block):len(block)], cands[i])
+		if !good(next) {
+			continue
+		}
+		if extendBlock(next, cands, i+1, n-1, budget, good, found) {
+			return true
+		}
+	}
+	return false
+}
+
+// candidates returns the words which pass the predicates on their own.
+// Since the predicates are hereditary, no other word can be in a block.
+func candidates(words []uint, preds []BasisPredicate) (c []uint) {
+	for _, v := range words {
+		if allHold(preds, []uint{v}) {
+			c = append(c, v)
+		}
+	}
+	return
+}
+
+func allHold(preds []BasisPredicate, b []uint) bool {
+	for _, p := range preds {
+		if !p(b) {
+			return false
+		}
+	}
+	return true
+}
+
+// spanKey identifies the span of an independent set by its reduced row
+// echelon form.
+func spanKey(b []uint) string {
+	rows, _ := rowReduce(b, uintBits)
+	return fmt.Sprintf("%x", rows)
+}
@@ -0,0 +1,124 @@
+package codeloops
+
+import (
+	"testing"
+)
+
+func TestPartitionGolay(t *testing.T) {
+	preds := []BasisPredicate{WeightsDivisibleBy(8), IsAssocCode}
+	parts, err := PartitionBasis(PartitionParams{Basis: GolayBasis, VDim: 6, WDim: 5, V: preds, W: preds})
+	if err != nil {
+		t.Fatalf("Failed to partition Golay: %s", err)
+	}
+	if len(parts) != 1 {
+		t.Fatalf("Expected the first solution only, got %d", len(parts))
+	}
+	p := parts[0]
+	b := p.Basis()
+	if len(p.V) != 6 || len(p.W) != 5 || len(b) != 12 || dependentVector(b) >= 0 {
+		t.Fatalf("Bad partition %x", b)
+	}
+	for _, blk := range [][]uint{p.V, p.W} {
+		if CheckTriplyEven(blk) != nil || !IsAssocCode(blk) {
+			t.Fatalf("Block %x fails the predicates", blk)
+		}
+	}
+	golay, _ := NewLinearCode(GolayBasis, 24)
+	for _, v := range b {
+		if !golay.Contains(v) {
+			t.Fatalf("0x%x isn't a Golay word", v)
+		}
+	}
+	cl, err := NewCL(CLParams{Basis: b})
+	if err != nil || cl.VerifyBasis() != nil {
+		t.Fatalf("Partitioned basis doesn't make a code loop: %v", err)
+	}
+	// Rest is completed from the given basis, not from the whole code.
+	for _, v := range p.Rest {
+		found := false
+		for _, g := range GolayBasis {
+			found = found || v == g
+		}
+		if !found {
+			t.Fatalf("Rest 0x%x isn't from the Golay basis", v)
+		}
+	}
+}
+
+func TestPartitionLimit(t *testing.T) {
+	// There's no split of Golay into two 6 dimensional triply even
+	// associative blocks, and the full search takes minutes.
+	preds := []BasisPredicate{WeightsDivisibleBy(8), IsAssocCode}
+	_, err := PartitionBasis(PartitionParams{Basis: GolayBasis, VDim: 6, WDim: 6, V: preds, W: preds, Limit: 20000})
+	if err == nil {
+		t.Fatalf("Expected the search to give up")
+	}
+	// The same search that succeeds by default fails with a tiny limit.
+	if _, err = PartitionBasis(PartitionParams{Basis: GolayBasis, VDim: 6, WDim: 5, V: preds, W: preds, Limit: 100}); err == nil {
+		t.Fatalf("Expected the search to give up after 100 blocks")
+	}
+}
+
+func TestPartitionAll(t *testing.T) {
+	// Every 2 dimensional V in F_2^4 (35 of them) has 16 complements W.
+	e8 := E8()
+	parts, err := PartitionBasis(PartitionParams{Basis: e8.Basis(), VDim: 2, WDim: 2, All: true})
+	if err != nil || len(parts) != 35*16 {
+		t.Fatalf("Expected 560 partitions of e8, got %d (%v)", len(parts), err)
+	}
+	seen := map[string]bool{}
+	for _, p := range parts {
+		k := spanKey(p.V) + spanKey(p.W)
+		if seen[k] {
+			t.Fatalf("Partition %x, %x repeated", p.V, p.W)
+		}
+		seen[k] = true
+	}
+
+	// A user predicate: only weight 4 words in the block. In e8 two weight
+	// 4 words always sum to another, so any V is fine, but W must avoid
+	// the all ones word as well as meeting V trivially.
+	only4 := func(b []uint) bool {
+		for _, v := range VectorSpace(b)[1:] {
+			if BitWeight(v) != 4 {
+				return false
+			}
+		}
+		return true
+	}
+	parts, err = PartitionBasis(PartitionParams{Basis: e8.Basis(), VDim: 1, WDim: 3, W: []BasisPredicate{only4}, All: true})
+	if err != nil {
+		t.Fatalf("Partition failed: %s", err)
+	}
+	for _, p := range parts {
+		if !only4(p.W) || len(p.Rest) != 0 {
+			t.Fatalf("Bad partition %x, %x, %x", p.V, p.W, p.Rest)
+		}
+	}
+
+	// The e8 loop isn't commutative, so it can't be all of V.
+	if _, err = PartitionBasis(PartitionParams{Basis: e8.Basis(), VDim: 4, V: []BasisPredicate{IsCommutativeCode}}); err == nil {
+		t.Fatalf("e8 isn't commutative, expected no solution")
+	}
+	if _, err = PartitionBasis(PartitionParams{Basis: e8.Basis(), VDim: 3, WDim: 2}); err == nil {
+		t.Fatalf("Blocks bigger than the code should fail")
+	}
+}
+
+func TestWeightsDivisibleBy(t *testing.T) {
+	rm14, _ := ReedMuller(1, 4)
+	for _, m := range []uint{1, 2, 4, 8, 16} {
+		want := true
+		for _, v := range rm14.Words() {
+			if BitWeight(v)%m != 0 {
+				want = false
+			}
+		}
+		if WeightsDivisibleBy(m)(rm14.Basis()) != want {
+			t.Fatalf("WeightsDivisibleBy(%d) wrong for RM(1,4)", m)
+		}
+	}
+	if !WeightsDivisibleBy(0)(rm14.Basis()) {
+		t.Fatalf("WeightsDivisibleBy(0) should hold like WeightsDivisibleBy(1)")
+	}
+}
@@ -0,0 +1,315 @@
+package codeloops
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Perm is a permutation of the points 0..n-1, stored as a slice of images,
+// so that p[i] is the image of i under p. For code automorphisms the points
+// are coordinate (bit) positions.
+type Perm []uint
+
+// IdentityPerm returns the identity permutation on n points.
+func IdentityPerm(n uint) Perm {
+	p := make(Perm, n)
+	for i := range p {
+		p[i] = uint(i)
+	}
+	return p
+}
+
+// NewPerm creates a permutation from a slice of images, checking that it
+// really is a permutation.
+func NewPerm(images []uint) (p Perm, e error) {
+	seen := make([]bool, len(images))
+	for i, img := range images {
+		if img >= uint(len(images)) {
+			e = fmt.Errorf("Bad image %d for point %d in permutation of degree %d", img, i, len(images))
+			return
+		}
+		if seen[img] {
+			e = fmt.Errorf("Image %d repeated in permutation", img)
+			return
+		}
+		seen[img] = true
+	}
+	p = append(Perm{}, images...)
+	return
+}
+
+// Degree returns the number of points the permutation acts on.
+func (p Perm) Degree() uint {
+	return uint(len(p))
+}
+
+// Mul returns the product pq. Permutations act on the right, so pq means
+// "first p, then q".
+func (p Perm) Mul(q Perm) Perm {
+	r := make(Perm, len(p))
+	for i, img := range p {
+		r[i] = q[img]
+	}
+	return r
+}
+
+// Inverse returns the inverse permutation.
+func (p Perm) Inverse() Perm {
+	r := make(Perm, len(p))
+	for i, img := range p {
+		r[img] = uint(i)
+	}
+	return r
+}
+
+// IsIdentity reports whether p fixes every point.
+func (p Perm) IsIdentity() bool {
+	for i, img := range p {
+		if img != uint(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal reports whether p and q are the same permutation.
+func (p Perm) Equal(q Perm) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ApplyVec permutes the coordinates of a vector, moving bit i to bit p[i].
+func (p Perm) ApplyVec(v uint) (res uint) {
+	for i := uint(0); v != 0; i++ {
+		if v&1 == 1 {
+			res |= 1 << p[i]
+		}
+		v >>= 1
+	}
+	return
+}
+
+// String displays the permutation in cycle notation, eg (0 1 2)(3 4).
+func (p Perm) String() string {
+	var sb strings.Builder
+	seen := make([]bool, len(p))
+	for i := range p {
+		if seen[i] || p[i] == uint(i) {
+			continue
+		}
+		sb.WriteString("(")
+		for j := uint(i); !seen[j]; j = p[j] {
+			seen[j] = true
+			if j != uint(i) {
+				sb.WriteString(" ")
+			}
+			sb.WriteString(strconv.Itoa(int(j)))
+		}
+		sb.WriteString(")")
+	}
+	if sb.Len() == 0 {
+		return "()"
+	}
+	return sb.String()
+}
+
+// PermGroup is a permutation group, stored as a base and strong generating
+// set computed with the Schreier-Sims algorithm.
+type PermGroup struct {
+	degree uint
+	gens   []Perm
+	base   []uint
+	// levels[i] are the strong generators which fix base[:i], ie the
+	// generators for the i'th group in the stabilizer chain.
+	levels [][]Perm
+	// trans[i][pt] maps base[i] to pt, or is nil if pt is not in the orbit
+	// of base[i] under the i'th stabilizer. orbits[i] lists the orbit in the
+	// order the points were found.
+	trans  [][]Perm
+	orbits [][]uint
+}
+
+// NewPermGroup returns the group of the given degree generated by gens.
+func NewPermGroup(degree uint, gens []Perm) (g *PermGroup, e error) {
+	g = new(PermGroup)
+	g.degree = degree
+	for _, p := range gens {
+		if p.Degree() != degree {
+			e = fmt.Errorf("Generator %s has degree %d, expected %d", p, p.Degree(), degree)
+			return
+		}
+		if !p.IsIdentity() {
+			g.gens = append(g.gens, p)
+		}
+	}
+	g.schreierSims()
+	return
+}
+
+// firstMoved returns the first point moved by p, or false if p is the
+// identity.
+func firstMoved(p Perm) (uint, bool) {
+	for i, img := range p {
+		if img != uint(i) {
+			return uint(i), true
+		}
+	}
+	return 0, false
+}
+
+// fixes reports whether p fixes every point in pts.
+func fixes(p Perm, pts []uint) bool {
+	for _, pt := range pts {
+		if p[pt] != pt {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *PermGroup) addBasePoint(pt uint) {
+	g.base = append(g.base, pt)
+	g.levels = append(g.levels, nil)
+	g.trans = append(g.trans, nil)
+	g.orbits = append(g.orbits, nil)
+}
+
+// buildOrbit recomputes the orbit and transversal for level i.
+func (g *PermGroup) buildOrbit(i int) {
+	t := make([]Perm, g.degree)
+	b := g.base[i]
+	t[b] = IdentityPerm(g.degree)
+	orbit := []uint{b}
+	for n := 0; n < len(orbit); n++ {
+		pt := orbit[n]
+		for _, s := range g.levels[i] {
+			img := s[pt]
+			if t[img] == nil {
+				t[img] = t[pt].Mul(s)
+				orbit = append(orbit, img)
+			}
+		}
+	}
+	g.trans[i] = t
+	g.orbits[i] = orbit
+}
+
+// strip sifts p through the stabilizer chain starting at the given level. It
+// returns the residue and the level at which sifting stopped, which is
+// len(g.base) if p sifted all the way through.
+func (g *PermGroup) strip(p Perm, level int) (Perm, int) {
+	for i := level; i < len(g.base); i++ {
+		u := g.trans[i][p[g.base[i]]]
+		if u == nil {
+			return p, i
+		}
+		p = p.Mul(u.Inverse())
+	}
+	return p, len(g.base)
+}
+
+// schreierSims builds the base and strong generating set. This is the
+// deterministic version, cf Holt, Handbook of Computational Group Theory,
+// 4.4.2.
+func (g *PermGroup) schreierSims() {
+
+	// Make sure no generator fixes the whole base.
+	for _, p := range g.gens {
+		if fixes(p, g.base) {
+			pt, _ := firstMoved(p)
+			g.addBasePoint(pt)
+		}
+	}
+	for i := range g.base {
+		for _, p := range g.gens {
+			if fixes(p, g.base[:i]) {
+				g.levels[i] = append(g.levels[i], p)
+			}
+		}
+		g.buildOrbit(i)
+	}
+
+	i := len(g.base) - 1
+	for i >= 0 {
+		restart := false
+		for n := 0; n < len(g.orbits[i]) && !restart; n++ {
+			pt := g.orbits[i][n]
+			for _, s := range g.levels[i] {
+				// Schreier generator u_pt * s * u_(pt^s)^-1 fixes base[i]
+				h := g.trans[i][pt].Mul(s).Mul(g.trans[i][s[pt]].Inverse())
+				if h.IsIdentity() {
+					continue
+				}
+				y, j := g.strip(h, i+1)
+				if j == len(g.base) && y.IsIdentity() {
+					continue
+				}
+				if j == len(g.base) {
+					moved, _ := firstMoved(y)
+					g.addBasePoint(moved)
+				}
+				for l := i + 1; l <= j; l++ {
+					g.levels[l] = append(g.levels[l], y)
+					g.buildOrbit(l)
+				}
+				i = j
+				restart = true
+				break
+			}
+		}
+		if !restart {
+			i--
+		}
+	}
+}
+
+// Degree returns the number of points the group acts on.
+func (g *PermGroup) Degree() uint {
+	return g.degree
+}
+
+// Generators returns the generators originally supplied (less any identity
+// elements).
+func (g *PermGroup) Generators() []Perm {
+	return g.gens
+}
+
+// Base returns the base of the stabilizer chain.
+func (g *PermGroup) Base() []uint {
+	return g.base
+}
+
+// StrongGenerators returns the strong generating set relative to Base().
+func (g *PermGroup) StrongGenerators() []Perm {
+	if len(g.levels) == 0 {
+		return nil
+	}
+	return g.levels[0]
+}
+
+// Order returns the number of elements in the group.
+func (g *PermGroup) Order() *big.Int {
+	order := big.NewInt(1)
+	for _, orbit := range g.orbits {
+		order.Mul(order, big.NewInt(int64(len(orbit))))
+	}
+	return order
+}
+
+// Contains tests group membership by sifting p through the stabilizer chain.
+func (g *PermGroup) Contains(p Perm) bool {
+	if p.Degree() != g.degree {
+		return false
+	}
+	y, j := g.strip(p, 0)
+	return j == len(g.base) && y.IsIdentity()
+}
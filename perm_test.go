@@ -0,0 +1,139 @@
+package codeloops
+
+import (
+	"testing"
+)
+
+func TestPermGroupSymmetric(t *testing.T) {
+	// (0 1) and (0 1 2 3 4) generate S5
+	g, err := NewPermGroup(5, []Perm{{1, 0, 2, 3, 4}, {1, 2, 3, 4, 0}})
+	if err != nil {
+		t.Fatalf("Failed to create group: %s", err)
+	}
+	if g.Order().Int64() != 120 {
+		t.Fatalf("Expected S5 to have order 120, got %s", g.Order())
+	}
+	if !g.Contains(Perm{4, 3, 2, 1, 0}) {
+		t.Fatalf("S5 doesn't contain (0 4)(1 3)")
+	}
+	// (0 1 2) and (2 3 4) generate A5
+	g, err = NewPermGroup(5, []Perm{{1, 2, 0, 3, 4}, {0, 1, 3, 4, 2}})
+	if err != nil {
+		t.Fatalf("Failed to create group: %s", err)
+	}
+	if g.Order().Int64() != 60 {
+		t.Fatalf("Expected A5 to have order 60, got %s", g.Order())
+	}
+	if g.Contains(Perm{1, 0, 2, 3, 4}) {
+		t.Fatalf("A5 contains the odd permutation (0 1)")
+	}
+}
+
+func TestPermString(t *testing.T) {
+	p := Perm{1, 2, 0, 4, 3, 5}
+	if p.String() != "(0 1 2)(3 4)" {
+		t.Fatalf("Expected (0 1 2)(3 4), got %s", p)
+	}
+	if !p.Mul(p.Inverse()).IsIdentity() {
+		t.Fatalf("p * p^-1 is not the identity")
+	}
+}
+
+func TestAutGroupHamming(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	g, err := cl.AutGroup()
+	if err != nil {
+		t.Fatalf("Failed to compute Aut(C): %s", err)
+	}
+	// AGL(3,2)
+	if g.Order().Int64() != 1344 {
+		t.Fatalf("Expected Aut(C) to have order 1344, got %s", g.Order())
+	}
+	for _, p := range g.StrongGenerators() {
+		if !cl.PreservesCode(p) {
+			t.Fatalf("Generator %s does not preserve the code", p)
+		}
+	}
+}
+
+func TestAutGroupGolay(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: GolayBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	g, err := cl.AutGroup()
+	if err != nil {
+		t.Fatalf("Failed to compute Aut(C): %s", err)
+	}
+	// M24
+	if g.Order().Int64() != 244823040 {
+		t.Fatalf("Expected Aut(C) to have order 244823040, got %s", g.Order())
+	}
+	for _, p := range g.StrongGenerators() {
+		if !cl.PreservesCode(p) {
+			t.Fatalf("Generator %s does not preserve the code", p)
+		}
+	}
+	// a transposition can't preserve a code with minimum weight 8
+	p := IdentityPerm(24)
+	p[0], p[1] = 1, 0
+	if g.Contains(p) {
+		t.Fatalf("M24 contains the transposition %s", p)
+	}
+}
+
+func TestLiftAutomorphismHamming(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis, Random: true})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	g, err := cl.AutGroup()
+	if err != nil {
+		t.Fatalf("Failed to compute Aut(C): %s", err)
+	}
+	elems := cl.LoopElems()
+	xy, fx, fy, fxfy, fxy := new(CLElem), new(CLElem), new(CLElem), new(CLElem), new(CLElem)
+	for i, p := range g.StrongGenerators() {
+		la, err := cl.LiftAutomorphism(p, uint(i))
+		if err != nil {
+			t.Fatalf("Failed to lift %s: %s", p, err)
+		}
+		// check f(xy) = f(x)f(y) directly on the loop elements
+		for _, x := range elems {
+			for _, y := range elems {
+				cl.Mul(&x, &y, xy)
+				la.Apply(xy, fxy)
+				la.Apply(&x, fx)
+				la.Apply(&y, fy)
+				cl.Mul(fx, fy, fxfy)
+				if fxy.sgn != fxfy.sgn || fxy.vec != fxfy.vec {
+					t.Fatalf("Lift of %s fails at %s, %s", p, &x, &y)
+				}
+			}
+		}
+	}
+}
+
+func TestLiftAutomorphismGolay(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: GolayBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	g, err := cl.AutGroup()
+	if err != nil {
+		t.Fatalf("Failed to compute Aut(C): %s", err)
+	}
+	p := g.StrongGenerators()[0]
+	if _, err := cl.LiftAutomorphism(p, 0xabc); err != nil {
+		t.Fatalf("Failed to lift %s: %s", p, err)
+	}
+	// A permutation which doesn't preserve the code can't be lifted
+	q := IdentityPerm(24)
+	q[0], q[1] = 1, 0
+	if _, err := cl.LiftAutomorphism(q, 0); err == nil {
+		t.Fatalf("Lifted %s, which doesn't preserve the code", q)
+	}
+}
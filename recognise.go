@@ -0,0 +1,519 @@
+package codeloops
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Recognising code loops.
+//
+// A loop L is a code loop iff it has a central subloop Z = {1, z} of order at
+// most 2 such that L/Z is an elementary abelian 2-group, and it is then
+// determined up to isomorphism by the squaring map P: L/Z -> Z [Gri86]. In
+// the code loop of a doubly even code, x^2 = (-1)^(|x|/4), so we need a
+// code whose weights satisfy |x|/4 = P(x) (mod 2).
+//
+// P is cubic on L/Z. Writing x = sum of x_i b_i over a basis,
+//
+//	P(x) = sum p_i x_i + sum c_ij x_i x_j + sum a_ijk x_i x_j x_k
+//
+// where p_i = P(b_i), c_ij is the commutator of b_i, b_j and a_ijk the
+// associator of b_i, b_j, b_k. A code is given by the columns g of its
+// generator matrix, and the word for x has a 1 in each coordinate with
+// x.g = 1. As a function of the 0/1 variables x_i,
+//
+//	[x.g = 1] = sum over nonempty T in supp(g) of (-2)^(|T|-1) prod x_i, i in T
+//
+// which mod 8 only has terms with |T| <= 3. So a code has |x| = 4P(x)
+// (mod 8) exactly when its basis words w_i satisfy
+//
+//	|w_i| = 4p_i (mod 8), |w_i w_j| = 2c_ij (mod 4), |w_i w_j w_k| = a_ijk (mod 2)
+//
+// and we need such words, independent, in at most 64 coordinates so they fit
+// in a uint. Writing down a column for each term of P is easy but far too
+// long: Golay subcodes of dimension 7 already need around 100 coordinates,
+// against 24 for the Golay code itself. Instead codeFromForms builds the
+// words one at a time. Given w_1..w_(j-1), the conditions on w_j which are
+// mod 2 (even weight, even overlap with each w_i, and the associators) are
+// linear, so we solve them, allowing a few fresh coordinates, and then sample
+// the solutions for ones which also meet the mod 4 and mod 8 conditions,
+// preferring those using the fewest fresh coordinates. Some choices leave
+// the linear conditions for a later word unsolvable, so this is a depth
+// first search with a budget, restarted with new random choices and more
+// fresh coordinates until it succeeds. For the Parker loop it finds a code
+// of length 24. It is an error if the search gives up.
+//
+// Once we have the code, the isomorphism is built the same way as a Hom,
+// and checked on every pair of elements. That check is what proves the
+// loop is a code loop, so the earlier steps only need to be right when it
+// is one.
+
+// Recognition is the result of recognising a loop given by a Cayley table
+// as a code loop.
+type Recognition struct {
+	// CL is the code loop built from the recovered basis.
+	CL *CL
+	// Basis is the recovered basis of a doubly even code.
+	Basis []uint
+	// Z is the central element playing the part of -1.
+	Z int
+	// Iso maps each element of the table to its image in CL.
+	Iso []CLElem
+}
+
+// ReadCayleyTable reads a Cayley table with one row per line. Numbers can be
+// separated by spaces, commas or brackets, so GAP style lists work, and
+// lines without numbers are skipped. Elements can be numbered from 0 or
+// from 1; the table returned is always 0-based.
+func ReadCayleyTable(r io.Reader) (table [][]int, e error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	min := -1
+	for line := 1; sc.Scan(); line++ {
+		fields := strings.FieldsFunc(sc.Text(), func(c rune) bool {
+			return c < '0' || c > '9'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		row := make([]int, len(fields))
+		for i, f := range fields {
+			if row[i], e = strconv.Atoi(f); e != nil {
+				return nil, fmt.Errorf("Bad number %q at line %d", f, line)
+			}
+			if min < 0 || row[i] < min {
+				min = row[i]
+			}
+		}
+		table = append(table, row)
+	}
+	if e = sc.Err(); e != nil {
+		return
+	}
+	if min == 1 {
+		for _, row := range table {
+			for i := range row {
+				row[i]--
+			}
+		}
+	}
+	return
+}
+
+// CayleyTable returns the Cayley table of the loop, numbering elements as
+// in LoopElems, from 0.
+func (cl *CL) CayleyTable() [][]int {
+	elems := cl.LoopElems()
+	res := new(CLElem)
+	table := make([][]int, len(elems))
+	for i := range elems {
+		table[i] = make([]int, len(elems))
+		for j := range elems {
+			cl.Mul(&elems[i], &elems[j], res)
+			table[i][j] = int(cl.vm[res.vec] + res.sgn*cl.size)
+		}
+	}
+	return table
+}
+
+// loopTable is a loop on 0..n-1 given by its multiplication, with its
+// identity. Usually mul looks up a Cayley table, but tests recognise big
+// loops directly, without materialising their tables.
+type loopTable struct {
+	mul func(x, y int) int
+	n   int
+	e   int
+}
+
+func newLoopTable(table [][]int) (lt *loopTable, err error) {
+	n := len(table)
+	if n == 0 {
+		return nil, fmt.Errorf("Empty Cayley table")
+	}
+	seen := make([]int, n)
+	for i, row := range table {
+		if len(row) != n {
+			return nil, fmt.Errorf("Row %d has %d entries, expected %d", i, len(row), n)
+		}
+		for _, x := range row {
+			if x < 0 || x >= n {
+				return nil, fmt.Errorf("Entry %d in row %d out of range", x, i)
+			}
+			if seen[x] == i+1 {
+				return nil, fmt.Errorf("Row %d repeats %d, not a Latin square", i, x)
+			}
+			seen[x] = i + 1
+		}
+	}
+	for j := 0; j < n; j++ {
+		for i := 0; i < n; i++ {
+			if seen[table[i][j]] == -(j + 1) {
+				return nil, fmt.Errorf("Column %d repeats %d, not a Latin square", j, table[i][j])
+			}
+			seen[table[i][j]] = -(j + 1)
+		}
+	}
+	lt = &loopTable{mul: func(x, y int) int { return table[x][y] }, n: n, e: -1}
+	for x := 0; x < n && lt.e < 0; x++ {
+		id := true
+		for y := 0; y < n && id; y++ {
+			id = table[x][y] == y && table[y][x] == y
+		}
+		if id {
+			lt.e = x
+		}
+	}
+	if lt.e < 0 {
+		return nil, fmt.Errorf("No identity element, not a loop")
+	}
+	return
+}
+
+// findZ returns the element which must play the part of -1.
+func (lt *loopTable) findZ() (z int, err error) {
+	z = -1
+	for x := 0; x < lt.n; x++ {
+		sq := lt.mul(x, x)
+		if sq == lt.e || sq == z {
+			continue
+		}
+		if z >= 0 {
+			return 0, fmt.Errorf("Squares %d and %d are both non-trivial, so no central subloop of order 2 holds every square", z, sq)
+		}
+		z = sq
+	}
+	if z < 0 {
+		// Every square is trivial. The only code loops like that are
+		// elementary abelian groups, where any non-identity element will do.
+		for x := 0; x < lt.n; x++ {
+			if x != lt.e {
+				z = x
+				break
+			}
+		}
+	}
+	if lt.mul(z, z) != lt.e {
+		return 0, fmt.Errorf("Element %d has order > 2", z)
+	}
+	for x := 0; x < lt.n; x++ {
+		if lt.mul(x, z) != lt.mul(z, x) {
+			return 0, fmt.Errorf("Element %d is not central", z)
+		}
+	}
+	return
+}
+
+// RecogniseCodeLoop decides whether the loop with the given (0-based)
+// Cayley table is a code loop. If it is, it returns a doubly even basis and
+// an isomorphism to the CL built from it. If not, the error says why.
+func RecogniseCodeLoop(table [][]int) (r *Recognition, err error) {
+	lt, err := newLoopTable(table)
+	if err != nil {
+		return
+	}
+	return lt.recognise()
+}
+
+// recognise does the work of RecogniseCodeLoop. lt must be a loop.
+func (lt *loopTable) recognise() (r *Recognition, err error) {
+	if lt.n < 4 || lt.n&(lt.n-1) != 0 {
+		return nil, fmt.Errorf("Order %d is not a power of 2 of at least 4", lt.n)
+	}
+	z, err := lt.findZ()
+	if err != nil {
+		return
+	}
+
+	// Build a basis of L/Z. vecOf[x] is the coset of x as a vector over the
+	// basis, and rep[v] is the product ((b_i b_j) b_k).. for the bits of v in
+	// increasing order, which is the representative with sign +.
+	n := lt.n
+	vecOf := make([]int, n)
+	for x := range vecOf {
+		vecOf[x] = -1
+	}
+	vecOf[lt.e], vecOf[z] = 0, 0
+	rep := []int{lt.e}
+	basisElems := []int{}
+	for x := 0; x < n; x++ {
+		if vecOf[x] >= 0 {
+			continue
+		}
+		j := len(basisElems)
+		basisElems = append(basisElems, x)
+		for v := 0; v < 1<<uint(j); v++ {
+			p := lt.mul(rep[v], x)
+			w := v | 1<<uint(j)
+			for _, y := range []int{p, lt.mul(p, z)} {
+				if vecOf[y] >= 0 {
+					return nil, fmt.Errorf("L/Z is not elementary abelian: %d is already in the span", y)
+				}
+				vecOf[y] = w
+			}
+			rep = append(rep, p)
+		}
+	}
+	k := uint(len(basisElems))
+	if len(rep) != n/2 {
+		return nil, fmt.Errorf("L/Z is not elementary abelian")
+	}
+
+	// The forms on the basis, with z as 1.
+	bit := func(x, y int) (uint, error) {
+		switch x {
+		case y:
+			return 0, nil
+		case lt.mul(y, z):
+			return 1, nil
+		}
+		return 0, fmt.Errorf("Elements %d and %d are not in the same coset of Z", x, y)
+	}
+	var p []uint
+	c := make([][]uint, k)
+	a := make([][][]uint, k)
+	for i, bi := range basisElems {
+		pi, _ := bit(lt.mul(bi, bi), lt.e)
+		p = append(p, pi)
+		c[i] = make([]uint, k)
+		a[i] = make([][]uint, k)
+		for j, bj := range basisElems {
+			if c[i][j], err = bit(lt.mul(bi, bj), lt.mul(bj, bi)); err != nil {
+				return nil, fmt.Errorf("Commutator of %d, %d: %s", bi, bj, err)
+			}
+			a[i][j] = make([]uint, k)
+			for l, bl := range basisElems {
+				if a[i][j][l], err = bit(lt.mul(lt.mul(bi, bj), bl), lt.mul(bi, lt.mul(bj, bl))); err != nil {
+					return nil, fmt.Errorf("Associator of %d, %d, %d: %s", bi, bj, bl, err)
+				}
+			}
+		}
+	}
+
+	basis, err := codeFromForms(p, c, a)
+	if err != nil {
+		return
+	}
+	cl, err := NewCL(CLParams{Basis: basis})
+	if err != nil {
+		return
+	}
+
+	// Signs along the chain of representatives, as in NewHom: rep[v] is
+	// rep[v'] b_j with j the top bit of v, and b_j maps to +w_j.
+	sign := make([]uint, n/2)
+	for v := 1; v < n/2; v++ {
+		j := uint(0)
+		for v>>(j+1) != 0 {
+			j++
+		}
+		x := uint(v) ^ 1<<j
+		if x != 0 {
+			sign[v] = sign[x] ^ cl.thetaByIdxFast(x, 1<<j)
+		}
+	}
+	r = &Recognition{CL: cl, Basis: basis, Z: z, Iso: make([]CLElem, n)}
+	for x := 0; x < n; x++ {
+		v := vecOf[x]
+		s := sign[v]
+		if x != rep[v] {
+			s ^= 1
+		}
+		r.Iso[x] = CLElem{sgn: s, vec: cl.vs[v]}
+	}
+	res := new(CLElem)
+	for x := 0; x < n; x++ {
+		for y := 0; y < n; y++ {
+			cl.Mul(&r.Iso[x], &r.Iso[y], res)
+			img := r.Iso[lt.mul(x, y)]
+			if res.sgn != img.sgn || res.vec != img.vec {
+				return nil, fmt.Errorf("Not a code loop: the map to the code loop of %x fails at %d, %d", basis, x, y)
+			}
+		}
+	}
+	return
+}
+
+// codeFromForms searches for a doubly even basis whose code loop has the
+// given squares, commutators and associators on the basis (see above).
+func codeFromForms(p []uint, c [][]uint, a [][][]uint) (basis []uint, err error) {
+	for _, fresh := range []uint{12, 16, 20} {
+		for seed := int64(0); seed < 8; seed++ {
+			fs := &formSearch{
+				p: p, c: c, a: a,
+				fresh:  fresh,
+				budget: 2000,
+				rng:    rand.New(rand.NewSource(seed)),
+			}
+			if basis = fs.extend(nil, 0); basis != nil {
+				return
+			}
+		}
+	}
+	return nil, fmt.Errorf("Failed to find a code of length at most %d with these forms", uintBits)
+}
+
+// formSearch is the state of one depth first search in codeFromForms.
+type formSearch struct {
+	p      []uint
+	c      [][]uint
+	a      [][][]uint
+	fresh  uint // fresh coordinates offered to each new word
+	budget int  // words left to try before giving up
+	rng    *rand.Rand
+}
+
+// extend completes rows, which use coordinates 0..n-1, to a full basis, or
+// returns nil if it can't within the budget.
+func (fs *formSearch) extend(rows []uint, n uint) []uint {
+	j := len(rows)
+	if j == len(fs.p) {
+		return rows
+	}
+	if fs.budget <= 0 {
+		return nil
+	}
+	fs.budget--
+	for _, w := range fs.candidates(rows, n) {
+		if res := fs.extend(append(rows[:j:j], w), n+BitWeight(w>>n)); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+// candidates returns a few words which can follow rows, fewest fresh
+// coordinates first. Fresh coordinates are packed down so they start at n.
+func (fs *formSearch) candidates(rows []uint, n uint) (ws []uint) {
+	const samples, keep = 4000, 6
+	j := len(rows)
+	nv := n + fs.fresh
+	if nv > uintBits {
+		nv = uintBits
+	}
+	// The mod 2 conditions: w.1 = 0, w.w_i = 0, w.(w_i w_l) = a_ilj.
+	eqs := []uint{1<<nv - 1}
+	rhs := []uint{0}
+	for i := 0; i < j; i++ {
+		eqs = append(eqs, rows[i])
+		rhs = append(rhs, 0)
+		for l := i + 1; l < j; l++ {
+			eqs = append(eqs, rows[i]&rows[l])
+			rhs = append(rhs, fs.a[i][l][j])
+		}
+	}
+	part, ker, ok := solveF2(eqs, rhs, nv)
+	if !ok {
+		return nil
+	}
+	cost := map[uint]int{}
+	for s := 0; s < samples; s++ {
+		w := part
+		for _, kv := range ker {
+			if fs.rng.Intn(2) == 1 {
+				w ^= kv
+			}
+		}
+		if BitWeight(w)%8 != 4*fs.p[j] {
+			continue
+		}
+		good := true
+		for i := 0; i < j && good; i++ {
+			good = BitWeight(w&rows[i])%4 == 2*fs.c[i][j]
+		}
+		if !good || dependentVector(append(rows[:j:j], w)) >= 0 {
+			continue
+		}
+		m := BitWeight(w >> n)
+		w = w&(1<<n-1) | (1<<m-1)<<n
+		if _, seen := cost[w]; !seen {
+			cost[w] = int(m)*300 + fs.rng.Intn(1000)
+			ws = append(ws, w)
+		}
+	}
+	sort.SliceStable(ws, func(x, y int) bool { return cost[ws[x]] < cost[ws[y]] })
+	if len(ws) > keep {
+		ws = ws[:keep]
+	}
+	return
+}
+
+// solveF2 solves eqs[t].x = rhs[t] over GF(2) for x with nv bits, returning
+// one solution and a basis of the solutions of the homogeneous system.
+func solveF2(eqs, rhs []uint, nv uint) (part uint, ker []uint, ok bool) {
+	// pivots[b] is a reduced equation with lowest bit b, and pivotRHS[b] its
+	// right hand side. Each pivot bit is clear in every other pivot.
+	pivots := map[uint]uint{}
+	pivotRHS := map[uint]uint{}
+	for t, v := range eqs {
+		r := rhs[t]
+		for b, pv := range pivots {
+			if v>>b&1 != 0 {
+				v ^= pv
+				r ^= pivotRHS[b]
+			}
+		}
+		if v == 0 {
+			if r != 0 {
+				return 0, nil, false
+			}
+			continue
+		}
+		low := uint(0)
+		for v>>low&1 == 0 {
+			low++
+		}
+		for b, pv := range pivots {
+			if pv>>low&1 != 0 {
+				pivots[b] ^= v
+				pivotRHS[b] ^= r
+			}
+		}
+		pivots[low], pivotRHS[low] = v, r
+	}
+	for b, r := range pivotRHS {
+		part |= r << b
+	}
+	for f := uint(0); f < nv; f++ {
+		if _, ok := pivots[f]; ok {
+			continue
+		}
+		v := uint(1) << f
+		for b, pv := range pivots {
+			if pv>>f&1 != 0 {
+				v |= 1 << b
+			}
+		}
+		ker = append(ker, v)
+	}
+	return part, ker, true
+}
+
+// dependentVector returns the index of the first vector which is in the
+// span of the ones before it, or -1 if they are independent.
+func dependentVector(vs []uint) int {
+	// pivots[b] is a reduced vector with top bit b
+	pivots := map[int]uint{}
+	for i, v := range vs {
+		for v != 0 {
+			top := 63
+			for v>>uint(top) == 0 {
+				top--
+			}
+			pv, ok := pivots[top]
+			if !ok {
+				pivots[top] = v
+				break
+			}
+			v ^= pv
+		}
+		if v == 0 {
+			return i
+		}
+	}
+	return -1
+}
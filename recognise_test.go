@@ -0,0 +1,187 @@
+package codeloops
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// relabel returns the table with the elements renamed by a random
+// permutation, so recognition can't lean on the LoopElems order.
+func relabel(table [][]int, seed int64) [][]int {
+	perm := rand.New(rand.NewSource(seed)).Perm(len(table))
+	out := make([][]int, len(table))
+	for i := range out {
+		out[i] = make([]int, len(table))
+	}
+	for i, row := range table {
+		for j, x := range row {
+			out[perm[i]][perm[j]] = perm[x]
+		}
+	}
+	return out
+}
+
+func TestRecogniseCodeLoops(t *testing.T) {
+	for _, p := range []CLParams{
+		{Basis: HammingBasis},
+		{Basis: HammingBasis, Random: true, Seed: 5},
+		{Basis: GolayBasis[:6]},
+		{Basis: GolayAwesumBasis[:5]},
+	} {
+		cl, err := NewCL(p)
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		r, err := RecogniseCodeLoop(relabel(cl.CayleyTable(), 1))
+		if err != nil {
+			t.Fatalf("Failed to recognise code loop of %x: %s", p.Basis, err)
+		}
+		if len(r.Basis) != len(p.Basis) {
+			t.Fatalf("Recovered basis %x has the wrong dimension", r.Basis)
+		}
+		if err = r.CL.VerifyBasis(); err != nil {
+			t.Fatalf("Recovered basis %x is not doubly even: %s", r.Basis, err)
+		}
+		if r.CL.IsAssoc() != cl.IsAssoc() || len(r.CL.Center()) != len(cl.Center()) {
+			t.Fatalf("Recovered loop has different invariants")
+		}
+	}
+}
+
+func TestRecogniseGroups(t *testing.T) {
+	cyclic := func(n int) [][]int {
+		table := make([][]int, n)
+		for i := range table {
+			table[i] = make([]int, n)
+			for j := range table[i] {
+				table[i][j] = (i + j) % n
+			}
+		}
+		return table
+	}
+	if _, err := RecogniseCodeLoop(cyclic(8)); err == nil {
+		t.Fatalf("Z8 is not a code loop")
+	}
+	// Z4 x Z2 is: it's the code loop of a weight 4 word and a weight 8 word.
+	z4z2 := make([][]int, 8)
+	for i := range z4z2 {
+		z4z2[i] = make([]int, 8)
+		for j := range z4z2[i] {
+			z4z2[i][j] = ((i%4+j%4)%4 + 4*((i/4+j/4)%2))
+		}
+	}
+	r, err := RecogniseCodeLoop(z4z2)
+	if err != nil {
+		t.Fatalf("Failed to recognise Z4 x Z2: %s", err)
+	}
+	if !r.CL.IsAssoc() || !r.CL.IsCommutative() {
+		t.Fatalf("Z4 x Z2 should give an abelian group, basis %x", r.Basis)
+	}
+	if _, err = RecogniseCodeLoop([][]int{{0, 1}, {1, 1}}); err == nil {
+		t.Fatalf("Non Latin square should be rejected")
+	}
+}
+
+func TestRecogniseBroken(t *testing.T) {
+	// A loop from a theta that isn't a code cocycle.
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	flipTheta(cl, 5, 9)
+	if _, err = RecogniseCodeLoop(cl.CayleyTable()); err == nil {
+		t.Fatalf("Broken loop was recognised as a code loop")
+	}
+}
+
+func TestReadCayleyTable(t *testing.T) {
+	table, err := ReadCayleyTable(bytes.NewBufferString("ct := [\n  [1,2],\n  [2,1]\n];\n"))
+	if err != nil {
+		t.Fatalf("Failed to read table: %s", err)
+	}
+	if len(table) != 2 || table[0][0] != 0 || table[1][0] != 1 || table[1][1] != 0 {
+		t.Fatalf("Bad table %v", table)
+	}
+}
+
+// clLoop returns the loop of cl numbered as in CayleyTable, without building
+// the table, which for the Parker loop would have 2^26 entries.
+func clLoop(cl *CL) *loopTable {
+	elems := cl.LoopElems()
+	res := new(CLElem)
+	return &loopTable{mul: func(x, y int) int {
+		cl.Mul(&elems[x], &elems[y], res)
+		return int(cl.vm[res.vec] + res.sgn*cl.size)
+	}, n: len(elems), e: 0}
+}
+
+func TestRecogniseLargeCodeLoops(t *testing.T) {
+	for _, b := range [][]uint{
+		GolayBasis[:7],
+		GolayBasis[:8],
+		GolayAwesumBasis[:8],
+		GolayBasis,
+	} {
+		cl, err := NewCL(CLParams{Basis: b})
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		r, err := clLoop(cl).recognise()
+		if err != nil {
+			t.Fatalf("Failed to recognise code loop of %x: %s", b, err)
+		}
+		if len(r.Basis) != len(b) {
+			t.Fatalf("Recovered basis %x has the wrong dimension", r.Basis)
+		}
+		if r.CL.IsAssoc() != cl.IsAssoc() || len(r.CL.Center()) != len(cl.Center()) {
+			t.Fatalf("Recovered loop has different invariants")
+		}
+	}
+}
+
+// formsOf returns the squares, commutators and associators of the code loop
+// of basis, as RecogniseCodeLoop reads them from a table.
+func formsOf(basis []uint) (p []uint, c [][]uint, a [][][]uint) {
+	k := len(basis)
+	c = make([][]uint, k)
+	a = make([][][]uint, k)
+	for i, bi := range basis {
+		p = append(p, BitWeight(bi)/4%2)
+		c[i] = make([]uint, k)
+		a[i] = make([][]uint, k)
+		for j, bj := range basis {
+			c[i][j] = BitWeight(bi&bj) / 2 % 2
+			a[i][j] = make([]uint, k)
+			for l, bl := range basis {
+				a[i][j][l] = BitWeight(bi&bj&bl) % 2
+			}
+		}
+	}
+	return
+}
+
+func TestCodeFromFormsGolaySubcodes(t *testing.T) {
+	// Every subcode spanned by 7 of the Golay basis vectors.
+	for m := uint(0); m < 1<<12; m++ {
+		if BitWeight(m) != 7 {
+			continue
+		}
+		var b []uint
+		for i, v := range GolayBasis {
+			if m>>uint(i)&1 != 0 {
+				b = append(b, v)
+			}
+		}
+		p, c, a := formsOf(b)
+		got, err := codeFromForms(p, c, a)
+		if err != nil {
+			t.Fatalf("No code for the forms of %x: %s", b, err)
+		}
+		p2, c2, a2 := formsOf(got)
+		if fmt.Sprint(p, c, a) != fmt.Sprint(p2, c2, a2) || dependentVector(got) >= 0 {
+			t.Fatalf("Code %x doesn't have the forms of %x", got, b)
+		}
+	}
+}
@@ -0,0 +1,293 @@
+// Package render draws bit matrices, like theta or the signs of a
+// multiplication table, as images. Every cell is a square of Pixel pixels,
+// coloured by its bit, and the whole square is framed by a black border with
+// a white margin of the same width outside it.
+package render
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/bnagy/codeloops"
+	"github.com/bnagy/codeloops/bitset"
+)
+
+// Matrix is a square bit matrix.
+type Matrix interface {
+	Size() int
+	At(i, j int) uint
+}
+
+// Palette holds the colours used for an image.
+type Palette struct {
+	Background color.Color // the margin outside the frame
+	Frame      color.Color
+	Zero, One  color.Color // cells
+}
+
+// BlackWhite draws set bits black on white, which is the only palette that
+// survives the trip to PBM intact.
+var BlackWhite = Palette{
+	Background: color.White,
+	Frame:      color.Black,
+	Zero:       color.White,
+	One:        color.Black,
+}
+
+// Options control the layout of an image. For the vector formats the sizes
+// are in points instead of pixels.
+type Options struct {
+	Pixel   int      // side of one cell, in pixels. Zero means 1.
+	Border  int      // width of the frame and of the margin. Zero for neither.
+	Palette *Palette // nil means BlackWhite
+
+	// Labels for the rows and columns, which are only drawn in the vector
+	// formats. See VectorLabels and ElemLabels. FontSize zero means 3/4 of
+	// a cell.
+	Labels   []string
+	FontSize float64
+}
+
+func (o Options) pixel() int {
+	if o.Pixel <= 0 {
+		return 1
+	}
+	return o.Pixel
+}
+
+func (o Options) palette() *Palette {
+	if o.Palette == nil {
+		return &BlackWhite
+	}
+	return o.Palette
+}
+
+// Bounds returns the size of the image for a matrix with n rows, including
+// the frame and margin on both sides.
+func (o Options) Bounds(n int) image.Rectangle {
+	side := n*o.pixel() + 4*o.Border
+	return image.Rect(0, 0, side, side)
+}
+
+// Image draws the matrix. The result is paletted with the four colours of
+// the Palette, in order, so it is cheap to encode.
+func Image(m Matrix, opts Options) *image.Paletted {
+	p := opts.palette()
+	img := image.NewPaletted(opts.Bounds(m.Size()), color.Palette{p.Background, p.Frame, p.Zero, p.One})
+	const (
+		bg = iota
+		frame
+		zero
+	)
+	// Everything starts as background, so fill the framed square, then the
+	// cells inside it.
+	b, px := opts.Border, opts.pixel()
+	inner := m.Size() * px
+	fill(img, image.Rect(b, b, 3*b+inner, 3*b+inner), frame)
+	for i := 0; i < m.Size(); i++ {
+		for j := 0; j < m.Size(); j++ {
+			x, y := 2*b+j*px, 2*b+i*px
+			fill(img, image.Rect(x, y, x+px, y+px), zero+uint8(m.At(i, j)&1))
+		}
+	}
+	return img
+}
+
+func fill(img *image.Paletted, r image.Rectangle, c uint8) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(r.Min.X, y):img.PixOffset(r.Max.X, y)]
+		for x := range row {
+			row[x] = c
+		}
+	}
+}
+
+// Format is an image file format.
+type Format int
+
+const (
+	PNG Format = iota
+	PGM        // binary greyscale, P5
+	PBM        // binary bitmap, P4
+	SVG
+	EPS
+	PDF
+)
+
+// Vector reports whether the format is drawn with vector operations rather
+// than as an image.Image.
+func (f Format) Vector() bool {
+	return f >= SVG
+}
+
+// FormatFromPath picks the Format from the extension of a file name.
+func FormatFromPath(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return PNG, nil
+	case ".pgm":
+		return PGM, nil
+	case ".pbm":
+		return PBM, nil
+	case ".svg":
+		return SVG, nil
+	case ".eps":
+		return EPS, nil
+	case ".pdf":
+		return PDF, nil
+	}
+	return 0, fmt.Errorf("Unknown image format for %s", path)
+}
+
+// Encode writes img in the given format. PGM and PBM are converted to grey,
+// and for PBM anything darker than mid grey is black. The vector formats
+// need the Matrix, so use Write for those.
+func Encode(w io.Writer, img image.Image, f Format) error {
+	switch f {
+	case PNG:
+		return png.Encode(w, img)
+	case PGM:
+		return writePGM(w, img)
+	case PBM:
+		return writePBM(w, img)
+	}
+	if f.Vector() {
+		return fmt.Errorf("Can't encode an image.Image as a vector format")
+	}
+	return fmt.Errorf("Unknown image format %d", f)
+}
+
+func grey(img image.Image, x, y int) uint8 {
+	return color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y
+}
+
+func writePGM(w io.Writer, img image.Image) error {
+	r := img.Bounds()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "P5\n%d %d\n255\n", r.Dx(), r.Dy())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			bw.WriteByte(grey(img, x, y))
+		}
+	}
+	return bw.Flush()
+}
+
+func writePBM(w io.Writer, img image.Image) error {
+	r := img.Bounds()
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "P4\n%d %d\n", r.Dx(), r.Dy())
+	row := make([]byte, (r.Dx()+7)/8)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for i := range row {
+			row[i] = 0
+		}
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if grey(img, x, y) < 0x80 {
+				i := x - r.Min.X
+				row[i/8] |= 0x80 >> uint(i%8)
+			}
+		}
+		bw.Write(row)
+	}
+	return bw.Flush()
+}
+
+// Write draws the matrix and encodes it, in any Format.
+func Write(w io.Writer, m Matrix, opts Options, f Format) error {
+	if f.Vector() {
+		return writeVector(w, m, opts, f)
+	}
+	return Encode(w, Image(m, opts), f)
+}
+
+// theta is a Matrix of theta over a list of vectors.
+type theta struct {
+	cl    *codeloops.CL
+	order []uint
+}
+
+func (t theta) Size() int { return len(t.order) }
+
+func (t theta) At(i, j int) uint {
+	res, _ := t.cl.ThetaByVec(t.order[i], t.order[j])
+	return res
+}
+
+// Theta returns theta(x,y) for x and y taken from order, which lists
+// vectors in the code. A nil order means the VectorSpace of the loop.
+// Vectors not in the code draw as 0.
+func Theta(cl *codeloops.CL, order []uint) Matrix {
+	if order == nil {
+		order = cl.VectorSpace()
+	}
+	return theta{cl, order}
+}
+
+// signs is a Matrix of the signs of products of loop elements.
+type signs struct {
+	cl    *codeloops.CL
+	elems []codeloops.CLElem
+}
+
+func (s signs) Size() int { return len(s.elems) }
+
+func (s signs) At(i, j int) uint {
+	res := new(codeloops.CLElem)
+	s.cl.Mul(&s.elems[i], &s.elems[j], res)
+	return res.Sign()
+}
+
+// Signs returns the sign of x·y for the first n elements of LoopElems,
+// 1 for negative. With n equal to Size() this is the top left quarter of
+// the multiplication table, and the rest is just copies of it with the
+// sign flipped, or not. Zero n means all 2*Size() elements.
+func Signs(cl *codeloops.CL, n int) Matrix {
+	elems := cl.LoopElems()
+	if n > 0 && n < len(elems) {
+		elems = elems[:n]
+	}
+	return signs{cl, elems}
+}
+
+// bits is a Matrix stored row by row in a Bitset.
+type bits struct {
+	b *bitset.Bitset
+	n int
+}
+
+func (b bits) Size() int { return b.n }
+
+func (b bits) At(i, j int) uint { return b.b.Get(uint(i*b.n + j)) }
+
+// Bitset returns the n x n Matrix stored row by row in b.
+func Bitset(b *bitset.Bitset, n int) (Matrix, error) {
+	if b.Len() < uint(n*n) {
+		return nil, fmt.Errorf("Bitset of length %d is too short for %d x %d", b.Len(), n, n)
+	}
+	return bits{b, n}, nil
+}
+
+// rows is a Matrix given as a slice of rows.
+type rows [][]uint
+
+func (r rows) Size() int { return len(r) }
+
+func (r rows) At(i, j int) uint { return r[i][j] }
+
+// Rows returns the Matrix with the given rows, which must all have
+// len(rows) entries.
+func Rows(r [][]uint) (Matrix, error) {
+	for i := range r {
+		if len(r[i]) != len(r) {
+			return nil, fmt.Errorf("Row %d has length %d, expected %d", i, len(r[i]), len(r))
+		}
+	}
+	return rows(r), nil
+}
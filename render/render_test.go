@@ -0,0 +1,117 @@
+package render
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/bnagy/codeloops"
+	"github.com/bnagy/codeloops/bitset"
+)
+
+func TestImageLayout(t *testing.T) {
+	// 2x2 identity matrix
+	b := bitset.New(4)
+	b.Set(0)
+	b.Set(3)
+	m, err := Bitset(b, 2)
+	if err != nil {
+		t.Fatalf("Failed to make matrix: %s", err)
+	}
+	img := Image(m, Options{Pixel: 3, Border: 2})
+	if img.Bounds().Dx() != 2*3+4*2 || img.Bounds().Dy() != 2*3+4*2 {
+		t.Fatalf("Wrong image size %v", img.Bounds())
+	}
+	for _, c := range []struct {
+		x, y int
+		want color.Color
+	}{
+		{0, 0, color.White}, // margin
+		{2, 2, color.Black}, // frame
+		{4, 4, color.Black}, // cell (0,0)
+		{7, 4, color.White}, // cell (0,1)
+		{9, 9, color.Black}, // cell (1,1)
+		{10, 10, color.Black},
+		{13, 13, color.White},
+	} {
+		if !sameColor(img.At(c.x, c.y), c.want) {
+			t.Fatalf("Wrong colour at %d, %d", c.x, c.y)
+		}
+	}
+	if _, err = Bitset(b, 3); err == nil {
+		t.Fatalf("Bitset should be too short")
+	}
+}
+
+func sameColor(a, b color.Color) bool {
+	r1, g1, b1, a1 := a.RGBA()
+	r2, g2, b2, a2 := b.RGBA()
+	return r1 == r2 && g1 == g2 && b1 == b2 && a1 == a2
+}
+
+func TestThetaMatrix(t *testing.T) {
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: codeloops.HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	m := Theta(cl, nil)
+	img := Image(m, Options{})
+	for i := 0; i < m.Size(); i++ {
+		for j := 0; j < m.Size(); j++ {
+			th, _ := cl.ThetaByIdx(uint(i), uint(j))
+			if m.At(i, j) != th || img.ColorIndexAt(j, i) != 2+uint8(th) {
+				t.Fatalf("Wrong theta at %d, %d", i, j)
+			}
+		}
+	}
+	s := Signs(cl, 0)
+	if s.Size() != 2*cl.Size() || s.At(1, 1) != codeloops.Neg || s.At(0, 1) != codeloops.Pos {
+		t.Fatalf("Wrong signs")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	b := bitset.New(100)
+	for i := uint(0); i < 100; i += 11 {
+		b.Set(i)
+	}
+	m, _ := Bitset(b, 10)
+	img := Image(m, Options{})
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, PBM); err != nil {
+		t.Fatalf("PBM failed: %s", err)
+	}
+	// 10 pixel rows pack to 2 bytes, the diagonal is set
+	want := append([]byte("P4\n10 10\n"), 0x80, 0, 0x40, 0)
+	if !bytes.HasPrefix(buf.Bytes(), want) || buf.Len() != len("P4\n10 10\n")+20 {
+		t.Fatalf("Bad PBM % x", buf.Bytes())
+	}
+
+	buf.Reset()
+	if err := Encode(&buf, img, PGM); err != nil {
+		t.Fatalf("PGM failed: %s", err)
+	}
+	hdr := "P5\n10 10\n255\n"
+	pix := buf.Bytes()[len(hdr):]
+	if len(pix) != 100 || pix[0] != 0 || pix[1] != 0xff || pix[11] != 0 {
+		t.Fatalf("Bad PGM % x", buf.Bytes())
+	}
+
+	buf.Reset()
+	if err := Encode(&buf, img, PNG); err != nil {
+		t.Fatalf("PNG failed: %s", err)
+	}
+	dec, err := png.Decode(&buf)
+	if err != nil || !sameColor(dec.At(5, 5), color.Black) || !sameColor(dec.At(4, 5), color.White) {
+		t.Fatalf("PNG doesn't round trip: %v", err)
+	}
+
+	if f, err := FormatFromPath("x/theta.PGM"); err != nil || f != PGM {
+		t.Fatalf("Expected PGM from extension")
+	}
+	if _, err := FormatFromPath("theta.gif"); err == nil {
+		t.Fatalf("Expected an error for gif")
+	}
+}
@@ -0,0 +1,259 @@
+package render
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// The vector formats draw the same picture as Image, optionally with row
+// and column labels, as a list of filled rectangles. Runs of set cells along
+// a row are merged, so even the 4096x4096 Parker theta stays a manageable
+// size. Text is Helvetica, which every PostScript and PDF reader has.
+
+type rect struct {
+	x, y, w, h float64
+	c          color.Color
+}
+
+type label struct {
+	x, y   float64 // baseline
+	anchor float64 // 0 for left, 0.5 centre, 1 right
+	s      string
+}
+
+// figure is laid out with y increasing downwards, as in SVG.
+type figure struct {
+	w, h     float64
+	fontSize float64
+	rects    []rect
+	labels   []label
+}
+
+// VectorLabels returns labels for the vectors of a k dimensional space in
+// VectorSpace order, one character per basis vector, so for k = 2 they are
+// 00, 10, 01, 11 as in the paper.
+func VectorLabels(k int) []string {
+	ls := make([]string, 1<<uint(k))
+	for i := range ls {
+		b := make([]byte, k)
+		for r := range b {
+			b[r] = '0' + byte(i>>uint(r)&1)
+		}
+		ls[i] = string(b)
+	}
+	return ls
+}
+
+// ElemLabels returns labels for the elements of a code loop of dimension k
+// in LoopElems order: the VectorLabels with a + prefix, then with a - prefix.
+func ElemLabels(k int) []string {
+	ls := []string{}
+	for _, sgn := range []string{"+", "-"} {
+		for _, l := range VectorLabels(k) {
+			ls = append(ls, sgn+l)
+		}
+	}
+	return ls
+}
+
+// helvetica holds advance widths, in thousandths of an em, for the
+// characters that turn up in labels. Only the PDF writer needs them, to
+// centre text, since it can't ask the font.
+var helvetica = map[rune]float64{' ': 278, '+': 584, '-': 333, '.': 278, ',': 278}
+
+func textWidth(s string, size float64) (w float64) {
+	for _, r := range s {
+		cw, ok := helvetica[r]
+		if !ok {
+			cw = 556 // all the digits, and near enough for most letters
+		}
+		w += cw
+	}
+	return w * size / 1000
+}
+
+func newFigure(m Matrix, opts Options) (*figure, error) {
+	n := m.Size()
+	if opts.Labels != nil && len(opts.Labels) != n {
+		return nil, fmt.Errorf("Have %d labels for a matrix of size %d", len(opts.Labels), n)
+	}
+	p := opts.palette()
+	cell, b := float64(opts.pixel()), float64(opts.Border)
+	inner := float64(n) * cell
+	f := &figure{fontSize: opts.FontSize}
+	if f.fontSize == 0 {
+		f.fontSize = cell * 3 / 4
+	}
+
+	// Room for the labels on the left and the top.
+	var left, top float64
+	if opts.Labels != nil {
+		for _, l := range opts.Labels {
+			if w := textWidth(l, f.fontSize); w > left {
+				left = w
+			}
+		}
+		left += f.fontSize / 2
+		top = f.fontSize * 3 / 2
+	}
+	f.w, f.h = left+inner+4*b, top+inner+4*b
+	ox, oy := left+2*b, top+2*b
+
+	f.rects = append(f.rects, rect{0, 0, f.w, f.h, p.Background})
+	if b > 0 {
+		f.rects = append(f.rects, rect{left + b, top + b, inner + 2*b, inner + 2*b, p.Frame})
+	}
+	f.rects = append(f.rects, rect{ox, oy, inner, inner, p.Zero})
+	for i := 0; i < n; i++ {
+		for j := 0; j < n; {
+			if m.At(i, j)&1 == 0 {
+				j++
+				continue
+			}
+			start := j
+			for j < n && m.At(i, j)&1 == 1 {
+				j++
+			}
+			f.rects = append(f.rects, rect{ox + float64(start)*cell, oy + float64(i)*cell, float64(j-start) * cell, cell, p.One})
+		}
+	}
+
+	for i, l := range opts.Labels {
+		mid := float64(i)*cell + cell/2
+		// Digits are about 0.7em high, so this centres them on the row.
+		f.labels = append(f.labels,
+			label{left - f.fontSize/4, oy + mid + 0.35*f.fontSize, 1, l},
+			label{ox + mid, top - f.fontSize/4, 0.5, l})
+	}
+	return f, nil
+}
+
+// num formats a coordinate to a thousandth of a point, which is plenty.
+func num(x float64) string {
+	s := strconv.FormatFloat(x, 'f', 3, 64)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
+
+func rgb(c color.Color) (r, g, b float64) {
+	r32, g32, b32, _ := c.RGBA()
+	return float64(r32) / 0xffff, float64(g32) / 0xffff, float64(b32) / 0xffff
+}
+
+func hexColor(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
+
+func writeVector(w io.Writer, m Matrix, opts Options, f Format) error {
+	fig, err := newFigure(m, opts)
+	if err != nil {
+		return err
+	}
+	switch f {
+	case SVG:
+		return fig.svg(w)
+	case EPS:
+		return fig.eps(w)
+	case PDF:
+		return fig.pdf(w)
+	}
+	return fmt.Errorf("Format %d is not a vector format", f)
+}
+
+func (f *figure) svg(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%spt\" height=\"%spt\" viewBox=\"0 0 %s %s\">\n",
+		num(f.w), num(f.h), num(f.w), num(f.h))
+	for _, r := range f.rects {
+		fmt.Fprintf(bw, "<rect x=\"%s\" y=\"%s\" width=\"%s\" height=\"%s\" fill=\"%s\"/>\n",
+			num(r.x), num(r.y), num(r.w), num(r.h), hexColor(r.c))
+	}
+	anchors := map[float64]string{0: "start", 0.5: "middle", 1: "end"}
+	for _, l := range f.labels {
+		var esc bytes.Buffer
+		xmlEscape(&esc, l.s)
+		fmt.Fprintf(bw, "<text x=\"%s\" y=\"%s\" font-family=\"Helvetica, Arial, sans-serif\" font-size=\"%s\" text-anchor=\"%s\">%s</text>\n",
+			num(l.x), num(l.y), num(f.fontSize), anchors[l.anchor], esc.String())
+	}
+	fmt.Fprintf(bw, "</svg>\n")
+	return bw.Flush()
+}
+
+func xmlEscape(b *bytes.Buffer, s string) {
+	strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").WriteString(b, s)
+}
+
+// psString escapes a string for a PostScript or PDF literal.
+func psString(s string) string {
+	return "(" + strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`).Replace(s) + ")"
+}
+
+// ops returns the drawing operators shared by EPS and PDF, which both have
+// y increasing upwards. text formats one label.
+func (f *figure) ops(fill func(r rect) string, text func(l label, y float64) string) string {
+	var b strings.Builder
+	for _, r := range f.rects {
+		b.WriteString(fill(rect{r.x, f.h - r.y - r.h, r.w, r.h, r.c}))
+	}
+	for _, l := range f.labels {
+		b.WriteString(text(l, f.h-l.y))
+	}
+	return b.String()
+}
+
+func (f *figure) eps(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%%!PS-Adobe-3.0 EPSF-3.0\n%%%%BoundingBox: 0 0 %d %d\n%%%%HiResBoundingBox: 0 0 %s %s\n%%%%EndComments\n",
+		int(f.w+0.999), int(f.h+0.999), num(f.w), num(f.h))
+	fmt.Fprintf(bw, "/Helvetica findfont %s scalefont setfont\n", num(f.fontSize))
+	bw.WriteString(f.ops(func(r rect) string {
+		cr, cg, cb := rgb(r.c)
+		return fmt.Sprintf("%s %s %s setrgbcolor %s %s %s %s rectfill\n",
+			num(cr), num(cg), num(cb), num(r.x), num(r.y), num(r.w), num(r.h))
+	}, func(l label, y float64) string {
+		return fmt.Sprintf("0 setgray %s %s moveto %s dup stringwidth pop %s mul neg 0 rmoveto show\n",
+			num(l.x), num(y), psString(l.s), num(l.anchor))
+	}))
+	fmt.Fprintf(bw, "showpage\n%%%%EOF\n")
+	return bw.Flush()
+}
+
+func (f *figure) pdf(w io.Writer) error {
+	content := f.ops(func(r rect) string {
+		cr, cg, cb := rgb(r.c)
+		return fmt.Sprintf("%s %s %s rg %s %s %s %s re f\n",
+			num(cr), num(cg), num(cb), num(r.x), num(r.y), num(r.w), num(r.h))
+	}, func(l label, y float64) string {
+		x := l.x - l.anchor*textWidth(l.s, f.fontSize)
+		return fmt.Sprintf("0 g BT /F1 %s Tf %s %s Td %s Tj ET\n", num(f.fontSize), num(x), num(y), psString(l.s))
+	})
+	objs := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>",
+			num(f.w), num(f.h)),
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, o := range objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, o)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
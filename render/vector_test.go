@@ -0,0 +1,66 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bnagy/codeloops"
+)
+
+func TestVectorLabels(t *testing.T) {
+	if got := strings.Join(VectorLabels(2), ", "); got != "00, 10, 01, 11" {
+		t.Fatalf("Expected the paper's ordering, got %s", got)
+	}
+	if got := ElemLabels(1); len(got) != 4 || got[1] != "+1" || got[2] != "-0" {
+		t.Fatalf("Bad element labels %v", got)
+	}
+}
+
+func TestVectorFormats(t *testing.T) {
+	cl, err := codeloops.NewCL(codeloops.CLParams{Basis: []uint{0x0f, 0x3c}})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	opts := Options{Pixel: 10, Border: 1, Labels: VectorLabels(2)}
+	m := Theta(cl, nil)
+	for _, c := range []struct {
+		f    Format
+		want []string
+	}{
+		{SVG, []string{"<svg ", `width="20" height="10" fill="#000000"/>`, ">01</text>"}},
+		{EPS, []string{"%!PS-Adobe-3.0 EPSF-3.0", "%%BoundingBox: 0 0 57 56", "(11) dup stringwidth", "rectfill"}},
+		{PDF, []string{"%PDF-1.4", "/BaseFont /Helvetica", "(10) Tj", "%%EOF"}},
+	} {
+		var buf bytes.Buffer
+		if err = Write(&buf, m, opts, c.f); err != nil {
+			t.Fatalf("Failed to write format %d: %s", c.f, err)
+		}
+		for _, s := range c.want {
+			if !strings.Contains(buf.String(), s) {
+				t.Fatalf("Format %d output is missing %q:\n%s", c.f, s, buf.String())
+			}
+		}
+		if c.f == PDF {
+			// The xref offsets must point at the objects.
+			out := buf.String()
+			xref := out[strings.Index(out, "xref\n"):]
+			for i := 1; i <= 5; i++ {
+				var off int
+				fmt.Sscanf(strings.Split(xref, "\n")[2+i], "%d", &off)
+				if !strings.HasPrefix(out[off:], fmt.Sprintf("%d 0 obj", i)) {
+					t.Fatalf("Bad xref offset for object %d", i)
+				}
+			}
+		}
+	}
+
+	opts.Labels = opts.Labels[:3]
+	if err = Write(new(bytes.Buffer), m, opts, SVG); err == nil {
+		t.Fatalf("Expected an error for the wrong number of labels")
+	}
+	if err = Encode(new(bytes.Buffer), Image(m, opts), PDF); err == nil {
+		t.Fatalf("Expected an error encoding an image as PDF")
+	}
+}
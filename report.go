@@ -0,0 +1,171 @@
+package codeloops
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CheckResult is the outcome of checking one identity over the whole loop.
+type CheckResult struct {
+	Name string
+	Pass bool
+	// Witness holds the vectors for the first violation found, in the order
+	// of the VectorSpace, or nil if the check passed.
+	Witness []uint
+	// Violations is the total number of vectors (or pairs, or triples)
+	// which fail the check.
+	Violations uint64
+	Elapsed    time.Duration
+}
+
+func (r CheckResult) String() string {
+	if r.Pass {
+		return fmt.Sprintf("%-14s PASS (%s)", r.Name, r.Elapsed)
+	}
+	w := []string{}
+	for _, v := range r.Witness {
+		w = append(w, fmt.Sprintf("%x", v))
+	}
+	return fmt.Sprintf("%-14s FAIL (%s) %d violations, first at %s", r.Name, r.Elapsed, r.Violations, strings.Join(w, ", "))
+}
+
+// VerifyReport diagnoses a cocycle. It checks normalization and the axioms
+// (S), (C), (A) from [Gri86] p. 225, along with the Moufang identity and
+// associativity (which a code loop need not satisfy).
+type VerifyReport struct {
+	Normalization CheckResult
+	S             CheckResult
+	C             CheckResult
+	A             CheckResult
+	Moufang       CheckResult
+	Assoc         CheckResult
+}
+
+// Results returns all of the checks in the report, in a fixed order.
+func (r *VerifyReport) Results() []CheckResult {
+	return []CheckResult{r.Normalization, r.S, r.C, r.A, r.Moufang, r.Assoc}
+}
+
+// CodeCocycle reports whether theta is a code cocycle, ie passed
+// normalization, (S), (C) and (A).
+func (r *VerifyReport) CodeCocycle() bool {
+	return r.Normalization.Pass && r.S.Pass && r.C.Pass && r.A.Pass
+}
+
+func (r *VerifyReport) String() string {
+	lines := []string{}
+	for _, res := range r.Results() {
+		lines = append(lines, res.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// VerifyReport checks every identity over all of the vectors, pairs or triples
+// that it applies to, and counts every violation instead of stopping at the
+// first one. For large loops this means O(N^3) work for the triple
+// identities, but it is done 64 triples at a time and split across
+// goroutines.
+func (cl *CL) VerifyReport() *VerifyReport {
+	r := new(VerifyReport)
+	vs := cl.VectorSpace()
+	n := int(cl.size)
+
+	r.Normalization = countCheck("Normalization", 1, n, 1, func(_, i, _ int) bool {
+		return cl.thetaByIdxFast(uint(i), 0) != 0 || cl.thetaByIdxFast(0, uint(i)) != 0
+	}, func(_, i, _ int) []uint { return []uint{vs[i]} })
+
+	r.S = countCheck("(S)", 1, n, 1, func(_, i, _ int) bool {
+		return cl.thetaByIdxFast(uint(i), uint(i)) != (BitWeight(vs[i])/4)%2
+	}, func(_, i, _ int) []uint { return []uint{vs[i]} })
+
+	r.C = countCheck("(C)", 1, n, n, func(_, i, j int) bool {
+		return cl.thetaByIdxFast(uint(i), uint(j))^cl.thetaByIdxFast(uint(j), uint(i)) != (BitWeight(vs[i]&vs[j])/2)%2
+	}, func(_, i, j int) []uint { return []uint{vs[i], vs[j]} })
+
+	// The triple identities are checked a row at a time, see rows.go, unless
+	// there is no theta table to take rows from.
+	if cl.theta == nil {
+		triple := func(i, j, k int) []uint { return []uint{vs[i], vs[j], vs[k]} }
+		r.A = countCheck("(A)", n, n, n, func(i, j, k int) bool {
+			return cl.failsA(uint(i), uint(j), uint(k))
+		}, triple)
+		r.Moufang = countCheck("Moufang", n, n, n, func(i, j, k int) bool {
+			return cl.failsMoufang(uint(i), uint(j), uint(k))
+		}, triple)
+		r.Assoc = countCheck("Associativity", n, n, n, func(i, j, k int) bool {
+			return cl.thetaByIdxFast(uint(i), uint(j^k))^cl.thetaByIdxFast(uint(j), uint(k))^
+				cl.thetaByIdxFast(uint(i^j), uint(k))^cl.thetaByIdxFast(uint(i), uint(j)) != 0
+		}, triple)
+		return r
+	}
+	r.A = cl.rowCheck("(A)", cl.assocFiller(true))
+	r.Moufang = cl.rowCheck("Moufang", cl.moufangFiller())
+	r.Assoc = cl.rowCheck("Associativity", cl.assocFiller(false))
+
+	return r
+}
+
+// countCheck runs bad over [0,ni) x [0,nj) x [0,nk), counting failures and
+// recording the first one. Rows are split across goroutines, and the counts
+// for each row are kept separately so that the witness is deterministic.
+func countCheck(name string, ni, nj, nk int, bad func(i, j, k int) bool, witness func(i, j, k int) []uint) (res CheckResult) {
+
+	start := time.Now()
+	res.Name = name
+	counts := make([]uint64, ni)
+	firsts := make([][2]int, ni)
+
+	workers := runtime.GOMAXPROCS(0)
+	rows := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rows {
+				for j := 0; j < nj; j++ {
+					for k := 0; k < nk; k++ {
+						if bad(i, j, k) {
+							if counts[i] == 0 {
+								firsts[i] = [2]int{j, k}
+							}
+							counts[i]++
+						}
+					}
+				}
+			}
+		}()
+	}
+	for i := 0; i < ni; i++ {
+		rows <- i
+	}
+	close(rows)
+	wg.Wait()
+
+	for i, c := range counts {
+		if c > 0 && res.Violations == 0 {
+			res.Witness = witness(i, firsts[i][0], firsts[i][1])
+		}
+		res.Violations += c
+	}
+	res.Pass = res.Violations == 0
+	res.Elapsed = time.Since(start)
+	return
+}
+
+// rowCheck counts the violations of a triple identity using whole rows.
+func (cl *CL) rowCheck(name string, filler func() func(i, j uint, buf []uint64)) (res CheckResult) {
+	start := time.Now()
+	res.Name = name
+	count, i, j, k, found := cl.scanRows(false, filler)
+	if found {
+		res.Witness = []uint{cl.vs[i], cl.vs[j], cl.vs[k]}
+	}
+	res.Violations = count
+	res.Pass = !found
+	res.Elapsed = time.Since(start)
+	return
+}
@@ -0,0 +1,132 @@
+package codeloops
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// The restricted theta is theta on V∪W, where V and W are spanned by the
+// two halves of the basis. For a suitably partitioned basis (see
+// cmd/partition) this is all that is needed to multiply in the loop, via the
+// alpha formula in ThetaAlphaByVec.
+//
+// The text format is one row per line, with one '0' or '1' per column and no
+// separators. Rows and columns are in the order V then W, with the zero
+// vector only appearing once (at the start of V), so for the Golay code
+// with a 6+6 split the table is 127x127. This is the format of
+// cmd/loop_pics/restricted_parker_theta.txt.
+
+// restrictedOrder returns the vectors of V∪W in the order of the text format.
+func (cl *CL) restrictedOrder() []uint {
+	return append(append([]uint{}, cl.vsV...), cl.vsW[1:]...)
+}
+
+// RestrictedTheta returns theta on V∪W, in the order of the text format.
+func (cl *CL) RestrictedTheta() [][]uint {
+	ord := cl.restrictedOrder()
+	t := make([][]uint, len(ord))
+	for i, x := range ord {
+		t[i] = make([]uint, len(ord))
+		for j, y := range ord {
+			t[i][j] = cl.alphaByVecFast(x, y)
+		}
+	}
+	return t
+}
+
+// ReadRestrictedTheta reads a square 0/1 table in the text format.
+func ReadRestrictedTheta(r io.Reader) (t [][]uint, e error) {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		s := strings.TrimSpace(sc.Text())
+		if s == "" {
+			continue
+		}
+		row := make([]uint, len(s))
+		for i, c := range s {
+			switch c {
+			case '0':
+			case '1':
+				row[i] = 1
+			default:
+				e = fmt.Errorf("Bad character %q at line %d, column %d", c, line, i+1)
+				return
+			}
+		}
+		if len(t) > 0 && len(row) != len(t[0]) {
+			e = fmt.Errorf("Line %d has %d columns, expected %d", line, len(row), len(t[0]))
+			return
+		}
+		t = append(t, row)
+	}
+	if e = sc.Err(); e != nil {
+		return
+	}
+	if len(t) == 0 || len(t) != len(t[0]) {
+		e = fmt.Errorf("Restricted theta must be square and non-empty")
+		return
+	}
+	return
+}
+
+// WriteRestrictedTheta writes a table in the text format.
+func WriteRestrictedTheta(w io.Writer, t [][]uint) error {
+	bw := bufio.NewWriter(w)
+	for _, row := range t {
+		for _, b := range row {
+			bw.WriteByte('0' + byte(b&1))
+		}
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// NewCLFromAlpha creates a code loop from a basis and the restricted theta
+// on V∪W, without building the full theta table. Products are calculated
+// with the alpha formula, so this only gives the right loop if the basis
+// has the partitioning property that the formula relies on. Operations
+// which need whole theta rows fall back to slower versions. Only (S) and
+// normalization are checked, so the table isn't trusted to be a code
+// cocycle, and IsAssoc and friends check the products rather than reading
+// the answer off the basis.
+func NewCLFromAlpha(basis []uint, restricted [][]uint) (cl *CL, e error) {
+	cl = new(CL)
+	cl.params = CLParams{Basis: basis}
+	cl.basis = basis
+	cl.basisLen = uint(len(basis))
+	cl.size = 1 << cl.basisLen
+	cl.initSpace()
+	cl.initAlpha()
+
+	ord := cl.restrictedOrder()
+	if len(restricted) != len(ord) {
+		return nil, fmt.Errorf("Need a %dx%d restricted theta for this basis, got %d rows", len(ord), len(ord), len(restricted))
+	}
+	for i, row := range restricted {
+		if len(row) != len(ord) {
+			return nil, fmt.Errorf("Row %d has %d columns, expected %d", i, len(row), len(ord))
+		}
+		for j, b := range row {
+			if b > 1 {
+				return nil, fmt.Errorf("Bad theta value %d at %d, %d", b, i, j)
+			}
+			if e = cl.setAlphaByVec(ord[i], ord[j], b); e != nil {
+				return nil, e
+			}
+		}
+	}
+	// (S) and normalization are cheap to check, and catch tables for the
+	// wrong basis or in the wrong order.
+	for _, x := range ord {
+		if cl.alphaByVecFast(x, x) != (BitWeight(x)/4)%2 {
+			return nil, fmt.Errorf("Restricted theta fails (S) at %x", x)
+		}
+		if cl.alphaByVecFast(0, x) != 0 || cl.alphaByVecFast(x, 0) != 0 {
+			return nil, fmt.Errorf("Restricted theta not normalized at %x", x)
+		}
+	}
+	return
+}
@@ -0,0 +1,84 @@
+package codeloops
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+const restrictedParkerFile = "cmd/loop_pics/restricted_parker_theta.txt"
+
+func TestRestrictedParker(t *testing.T) {
+	data, err := os.ReadFile(restrictedParkerFile)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %s", restrictedParkerFile, err)
+	}
+	rt, err := ReadRestrictedTheta(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Failed to parse restricted theta: %s", err)
+	}
+	alpha, err := NewCLFromAlpha(GolayAwesumBasis, rt)
+	if err != nil {
+		t.Fatalf("Failed to create CL from alpha: %s", err)
+	}
+	full, err := NewCL(CLParams{Basis: GolayAwesumBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+
+	// The writer should reproduce the shipped file exactly.
+	var buf bytes.Buffer
+	WriteRestrictedTheta(&buf, full.RestrictedTheta())
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Fatalf("Restricted theta from NewCL doesn't match %s", restrictedParkerFile)
+	}
+
+	// Theta decides every product, up to the signs of the factors.
+	for i := uint(0); i < full.size; i++ {
+		for j := uint(0); j < full.size; j++ {
+			if alpha.thetaByIdxFast(i, j) != full.thetaByIdxFast(i, j) {
+				t.Fatalf("Theta differs at %x, %x", full.vs[i], full.vs[j])
+			}
+		}
+	}
+	elems := full.LoopElems()
+	res, res2 := new(CLElem), new(CLElem)
+	for i := 0; i < len(elems); i += 31 {
+		for j := 0; j < len(elems); j += 29 {
+			full.Mul(&elems[i], &elems[j], res)
+			alpha.Mul(&elems[i], &elems[j], res2)
+			if *res != *res2 {
+				t.Fatalf("Products differ for %v * %v: %v vs %v", elems[i], elems[j], res, res2)
+			}
+		}
+	}
+}
+
+func TestRestrictedBad(t *testing.T) {
+	if _, err := ReadRestrictedTheta(bytes.NewBufferString("01\n0\n")); err == nil {
+		t.Fatalf("Ragged table should fail to parse")
+	}
+	if _, err := ReadRestrictedTheta(bytes.NewBufferString("01\n02\n")); err == nil {
+		t.Fatalf("Bad character should fail to parse")
+	}
+	if _, err := NewCLFromAlpha(GolayAwesumBasis, [][]uint{{0}}); err == nil {
+		t.Fatalf("Wrong size table should be rejected")
+	}
+}
+
+func TestRestrictedUnverified(t *testing.T) {
+	full, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	r := full.RestrictedTheta()
+	// Off the diagonal, so (S) still passes.
+	r[1][2] ^= 1
+	cl, err := NewCLFromAlpha(HammingBasis, r)
+	if err != nil {
+		t.Fatalf("Failed to create CL from alpha: %s", err)
+	}
+	if cl.cocycle {
+		t.Fatalf("Unverified restricted theta marked as a code cocycle")
+	}
+}
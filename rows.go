@@ -0,0 +1,329 @@
+package codeloops
+
+import (
+	"fmt"
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// Identity checking with whole theta rows.
+//
+// Because the vector space is built from the bits of the index, we have
+// vs[i]^vs[j] == vs[i^j]. That means that for fixed x and y, each term of
+// the cocycle identities is a function of z which is either a row of theta
+// (theta(y,z) is row y), a row with its index space permuted by XOR
+// (theta(x,y+z) is row x with bit k moved to k^j), a column of theta (which
+// is a row of the transpose), a constant, or a linear function of z (the
+// associator |x&y&z| is linear in z). All of these can be built a word at a
+// time, so each identity can be checked for 64 values of z with a handful of
+// XORs, and the violations counted with a popcount.
+
+// xorSwap holds the masks to swap adjacent blocks of 1, 2, 4.. 32 bits.
+var xorSwap = [6]uint64{
+	0x5555555555555555,
+	0x3333333333333333,
+	0x0f0f0f0f0f0f0f0f,
+	0x00ff00ff00ff00ff,
+	0x0000ffff0000ffff,
+	0x00000000ffffffff,
+}
+
+// charLow[m] is the first word of the row k -> |k & m| mod 2, for m < 64.
+var charLow [64]uint64
+
+func init() {
+	for m := uint(0); m < 64; m++ {
+		for k := uint(0); k < 64; k++ {
+			if bits.OnesCount(k&m)%2 == 1 {
+				charLow[m] |= 1 << k
+			}
+		}
+	}
+}
+
+// thetaRow returns row i of theta, ie theta(vs[i], z) for every z. This is
+// the actual storage, not a copy.
+func (cl *CL) thetaRow(i uint) []uint64 {
+	return cl.theta.Words()[i*cl.thetaStride : (i+1)*cl.thetaStride]
+}
+
+// ThetaRow returns a copy of row i of theta, packed into words so that
+// theta(vs[i], vs[j]) is bit j%64 of word j/64.
+func (cl *CL) ThetaRow(i uint) ([]uint64, error) {
+	if cl.theta == nil {
+		return nil, fmt.Errorf("Loop has no theta table")
+	}
+	if i >= cl.size {
+		return nil, fmt.Errorf("Row %d out of range for theta with %d rows", i, cl.size)
+	}
+	return append([]uint64{}, cl.thetaRow(i)...), nil
+}
+
+// EqualTheta reports whether two loops have the same basis and identical
+// cocycles, comparing a row at a time. Loops without a theta table (built
+// with NewCLFromAlpha, or closed) are never equal.
+func (cl *CL) EqualTheta(other *CL) bool {
+	if cl.theta == nil || other.theta == nil || !equalUints(cl.basis, other.basis) {
+		return false
+	}
+	for i := uint(0); i < cl.size; i++ {
+		a, b := cl.thetaRow(i), other.thetaRow(i)
+		for w := range a {
+			if a[w] != b[w] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// rowMask is the mask of valid bits in each word of a row. Rows are only
+// partly filled when the loop has fewer than 64 vectors.
+func (cl *CL) rowMask() uint64 {
+	if cl.size >= 64 {
+		return ^uint64(0)
+	}
+	return 1<<cl.size - 1
+}
+
+// swapBits moves bit t of x to bit t^b, for b < 64.
+func swapBits(x uint64, b uint) uint64 {
+	for t := uint(0); t < 6; t++ {
+		if b&(1<<t) != 0 {
+			s := uint(1) << t
+			x = (x&xorSwap[t])<<s | (x>>s)&xorSwap[t]
+		}
+	}
+	return x
+}
+
+// xorRow sets dst[k] ^= src[k^j] for every bit k in the row.
+func xorRow(dst, src []uint64, j uint) {
+	jw, jb := j>>6, j&63
+	for w := range dst {
+		dst[w] ^= swapBits(src[uint(w)^jw], jb)
+	}
+}
+
+// rowShifts caches all 64 in-word permutations of one row, which turns
+// xorRow into plain word XORs when the same row is used with many j.
+type rowShifts struct {
+	row   uint
+	ok    bool
+	words [64][]uint64
+}
+
+func newRowShifts(stride uint) *rowShifts {
+	rs := new(rowShifts)
+	for b := range rs.words {
+		rs.words[b] = make([]uint64, stride)
+	}
+	return rs
+}
+
+func (rs *rowShifts) load(row uint, src []uint64) {
+	if rs.ok && rs.row == row {
+		return
+	}
+	for b := range rs.words {
+		for w, x := range src {
+			rs.words[b][w] = swapBits(x, uint(b))
+		}
+	}
+	rs.row, rs.ok = row, true
+}
+
+// xorInto is xorRow(dst, src, j) for the cached row.
+func (rs *rowShifts) xorInto(dst []uint64, j uint) {
+	jw, sh := j>>6, rs.words[j&63]
+	for w := range dst {
+		dst[w] ^= sh[uint(w)^jw]
+	}
+}
+
+// xorChar sets dst[k] ^= |k & m| mod 2, where the bits of k pick out basis
+// vectors, so this is any linear function on the vector space.
+func xorChar(dst []uint64, m uint) {
+	low, high := charLow[m&63], m>>6
+	for w := range dst {
+		if bits.OnesCount(uint(w)&high)%2 == 1 {
+			dst[w] ^= ^low
+		} else {
+			dst[w] ^= low
+		}
+	}
+}
+
+// assocMask returns the linear function z -> |vs[i] & vs[j] & z| mod 2 as a
+// mask on the index bits of z.
+func (cl *CL) assocMask(i, j uint) (m uint) {
+	xy := cl.vs[i] & cl.vs[j]
+	for t, b := range cl.basis {
+		m |= (BitWeight(xy&b) % 2) << uint(t)
+	}
+	return
+}
+
+// transpose returns theta with rows and columns swapped, in the same packed
+// layout, so that columns can be used as rows.
+func (cl *CL) transpose() []uint64 {
+	t := make([]uint64, len(cl.theta.Words()))
+	for i := uint(0); i < cl.size; i++ {
+		for j := uint(0); j < cl.size; j++ {
+			if cl.thetaBit(i, j) != 0 {
+				t[j*cl.thetaStride+i>>6] |= 1 << (i & 63)
+			}
+		}
+	}
+	return t
+}
+
+// The row fillers below set buf to the violations of an identity for the
+// vectors with indices i, j and every z. Each worker goroutine gets its own
+// filler, so they can cache permutations of the rows for the current i.
+
+// assocFiller: theta(x,y+z) + theta(y,z) + theta(x+y,z) + theta(x,y), plus
+// |x&y&z| if withA is set, which gives the (A) identity.
+func (cl *CL) assocFiller(withA bool) func() func(i, j uint, buf []uint64) {
+	return func() func(i, j uint, buf []uint64) {
+		rs := newRowShifts(cl.thetaStride)
+		return func(i, j uint, buf []uint64) {
+			rs.load(i, cl.thetaRow(i))
+			copy(buf, cl.thetaRow(j))
+			rs.xorInto(buf, j)
+			row := cl.thetaRow(i ^ j)
+			var c uint64
+			if cl.thetaBit(i, j) != 0 {
+				c = ^uint64(0)
+			}
+			for w := range buf {
+				buf[w] ^= row[w] ^ c
+			}
+			if withA {
+				xorChar(buf, cl.assocMask(i, j))
+			}
+		}
+	}
+}
+
+// moufangFiller: t(x,y) + t(z,x) + t(x+y,z+x) + t(y,z) + t(x,y+z) + t(x+y+z,x)
+// where the column terms come from the transpose of theta.
+func (cl *CL) moufangFiller() func() func(i, j uint, buf []uint64) {
+	tr := cl.transpose()
+	st := cl.thetaStride
+	return func() func(i, j uint, buf []uint64) {
+		rows, cols := newRowShifts(st), newRowShifts(st)
+		return func(i, j uint, buf []uint64) {
+			rows.load(i, cl.thetaRow(i))
+			cols.load(i, tr[i*st:(i+1)*st])
+			copy(buf, tr[i*st:(i+1)*st])
+			xorRow(buf, cl.thetaRow(i^j), i)
+			rows.xorInto(buf, j)
+			cols.xorInto(buf, i^j)
+			row := cl.thetaRow(j)
+			var c uint64
+			if cl.thetaBit(i, j) != 0 {
+				c = ^uint64(0)
+			}
+			for w := range buf {
+				buf[w] ^= row[w] ^ c
+			}
+		}
+	}
+}
+
+// scanRows runs a filler over every pair of indices (i, j), and collects the
+// violations in the resulting rows. The work is split by i across
+// goroutines, and the witness is always the first failing triple in index
+// order. If stopEarly is set, the scan stops as soon as the witness is
+// known and the count is meaningless.
+func (cl *CL) scanRows(stopEarly bool, filler func() func(i, j uint, buf []uint64)) (count uint64, wi, wj, wk uint, found bool) {
+
+	n := cl.size
+	mask := cl.rowMask()
+	workers := runtime.GOMAXPROCS(0)
+	var next int64 = -1
+	best := int64(n)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]uint64, cl.thetaStride)
+			fill := filler()
+			var local uint64
+			defer func() {
+				mu.Lock()
+				count += local
+				mu.Unlock()
+			}()
+			for {
+				i := atomic.AddInt64(&next, 1)
+				if i >= int64(n) || (stopEarly && i > atomic.LoadInt64(&best)) {
+					return
+				}
+				for j := uint(0); j < n; j++ {
+					if stopEarly && atomic.LoadInt64(&best) < i {
+						return
+					}
+					fill(uint(i), j, buf)
+					var first uint
+					hit := false
+					for wd, x := range buf {
+						x &= mask
+						if x == 0 {
+							continue
+						}
+						if !hit {
+							first, hit = uint(wd)*64+uint(bits.TrailingZeros64(x)), true
+						}
+						local += uint64(bits.OnesCount64(x))
+					}
+					if !hit {
+						continue
+					}
+					mu.Lock()
+					if i < best || (i == best && !found) {
+						wi, wj, wk, found = uint(i), j, first, true
+						atomic.StoreInt64(&best, i)
+					}
+					mu.Unlock()
+					if stopEarly {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return
+}
+
+// verifyAssocRows is the row version of verifyAssoc2.
+func (cl *CL) verifyAssocRows() bool {
+	_, _, _, _, found := cl.scanRows(true, cl.assocFiller(false))
+	return !found
+}
+
+// verifyARows checks (A) for all triples, row by row.
+func (cl *CL) verifyARows() error {
+	_, i, j, k, found := cl.scanRows(true, cl.assocFiller(true))
+	if found {
+		return fmt.Errorf("Theta fails (A) at %x, %x, %x", cl.vs[i], cl.vs[j], cl.vs[k])
+	}
+	return nil
+}
+
+// verifyMoufangRows is the row version of verifyMoufang2, and gives the
+// same witness.
+func (cl *CL) verifyMoufangRows() error {
+	_, i, j, k, found := cl.scanRows(true, cl.moufangFiller())
+	if found {
+		return fmt.Errorf("Code loop failed Moufang identity at %x, %x, %x", cl.vs[i], cl.vs[j], cl.vs[k])
+	}
+	return nil
+}
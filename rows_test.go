@@ -0,0 +1,164 @@
+package codeloops
+
+import (
+	"testing"
+)
+
+func TestRowsMatchHamming(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		cl, err := NewCL(CLParams{Basis: HammingBasis, Random: true})
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		if err = cl.verifyARows(); err != nil {
+			t.Fatalf("Hamming theta failed (A): %s", err)
+		}
+		if err = cl.verifyMoufangRows(); err != nil {
+			t.Fatalf("Hamming loop not Moufang: %s", err)
+		}
+		if cl.verifyAssocRows() {
+			t.Fatalf("Hamming loop is associative.")
+		}
+	}
+	cl, err := NewCL(CLParams{Basis: badHammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	slow, fast := cl.verifyMoufang2(), cl.verifyMoufangRows()
+	if slow == nil || fast == nil || slow.Error() != fast.Error() {
+		t.Fatalf("Row witness %q doesn't match %q", fast, slow)
+	}
+}
+
+func TestRowsMatchGolay(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: badGolayBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	slow, fast := cl.verifyMoufang2(), cl.verifyMoufangRows()
+	if slow == nil || fast == nil || slow.Error() != fast.Error() {
+		t.Fatalf("Row witness %q doesn't match %q", fast, slow)
+	}
+	// every 5 element subset, to get some groups and some loops
+	SetCombinationsWithoutReplacement(uint(len(GolayBasis)), 5, func(s []uint) {
+		b := []uint{}
+		for _, idx := range s {
+			b = append(b, GolayBasis[idx])
+		}
+		cl, err := NewCL(CLParams{Basis: b, Random: true})
+		if err != nil {
+			t.Fatalf("Failed to create CL: %s", err)
+		}
+		if cl.verifyAssocRows() != cl.verifyAssoc2() {
+			t.Fatalf("verifyAssocRows and verifyAssoc2 disagree for %x", b)
+		}
+		if err = cl.verifyARows(); err != nil {
+			t.Fatalf("Golay subcode failed (A): %s", err)
+		}
+	})
+}
+
+func TestGolayA(t *testing.T) {
+	// (A) over all 2^36 triples
+	cl, err := NewCL(CLParams{Basis: GolayBasis, Random: true})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	if err = cl.verifyARows(); err != nil {
+		t.Fatalf("Golay theta failed (A): %s", err)
+	}
+}
+
+func TestReportCountsMatch(t *testing.T) {
+	// The row counts must agree with counting one triple at a time.
+	cl, err := NewCL(CLParams{Basis: badHammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	r := cl.VerifyReport()
+	n := int(cl.size)
+	a := countCheck("(A)", n, n, n, func(i, j, k int) bool {
+		return cl.failsA(uint(i), uint(j), uint(k))
+	}, func(i, j, k int) []uint { return []uint{cl.vs[i], cl.vs[j], cl.vs[k]} })
+	if a.Violations != r.A.Violations || a.Witness[0] != r.A.Witness[0] ||
+		a.Witness[1] != r.A.Witness[1] || a.Witness[2] != r.A.Witness[2] {
+		t.Fatalf("Row check %s doesn't match %s", r.A, a)
+	}
+	var moufang uint64
+	for x := uint(0); x < cl.size; x++ {
+		for y := uint(0); y < cl.size; y++ {
+			for z := uint(0); z < cl.size; z++ {
+				moufang += uint64(cl.thetaBit(x, y) ^ cl.thetaBit(z, x) ^ cl.thetaBit(x^y, z^x) ^
+					cl.thetaBit(y, z) ^ cl.thetaBit(x, y^z) ^ cl.thetaBit(x^y^z, x))
+			}
+		}
+	}
+	if moufang != r.Moufang.Violations {
+		t.Fatalf("Expected %d Moufang violations, got %d", moufang, r.Moufang.Violations)
+	}
+}
+
+func BenchmarkVerifyARowsGolay(b *testing.B) {
+	cl, err := NewCL(CLParams{Basis: GolayBasis})
+	if err != nil {
+		b.Fatalf("Failed to create CL: %s", err)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err = cl.verifyARows(); err != nil {
+			b.Fatalf("%s", err)
+		}
+	}
+}
+
+// The first half of the awesum basis is a group with 64 elements, so the
+// associativity checks have to look at every triple.
+func BenchmarkVerifyAssoc2Group64(b *testing.B) {
+	cl, err := NewCL(CLParams{Basis: GolayAwesumBasis[:6]})
+	if err != nil {
+		b.Fatalf("Failed to create CL: %s", err)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if !cl.verifyAssoc2() {
+			b.Fatalf("V didn't form a group!")
+		}
+	}
+}
+
+func BenchmarkVerifyAssocRowsGroup64(b *testing.B) {
+	cl, err := NewCL(CLParams{Basis: GolayAwesumBasis[:6]})
+	if err != nil {
+		b.Fatalf("Failed to create CL: %s", err)
+	}
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if !cl.verifyAssocRows() {
+			b.Fatalf("V didn't form a group!")
+		}
+	}
+}
+
+func TestEqualTheta(t *testing.T) {
+	a, _ := NewCL(CLParams{Basis: GolayBasis[:4]})
+	b, _ := NewCL(CLParams{Basis: GolayBasis[:4]})
+	c, _ := NewCL(CLParams{Basis: GolayBasis[4:8]})
+	if !a.EqualTheta(b) {
+		t.Fatalf("Loops from the same basis should be equal")
+	}
+	if a.EqualTheta(c) {
+		t.Fatalf("Loops from different bases shouldn't be equal")
+	}
+
+	full, _ := NewCL(CLParams{Basis: GolayAwesumBasis})
+	alphaOnly, err := NewCLFromAlpha(GolayAwesumBasis, full.RestrictedTheta())
+	if err != nil {
+		t.Fatalf("Failed to create CL from alpha: %s", err)
+	}
+	if full.EqualTheta(alphaOnly) || alphaOnly.EqualTheta(full) {
+		t.Fatalf("A loop without theta shouldn't be equal to anything")
+	}
+	if _, err = alphaOnly.ThetaRow(0); err == nil {
+		t.Fatalf("ThetaRow should fail without theta")
+	}
+}
@@ -0,0 +1,97 @@
+package codeloops
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// SampleReport is the result of checking the triple identities on random
+// triples, for loops which are too big to check exhaustively.
+//
+// Sampling can only ever find violations, not rule them out. If a fraction
+// eps of all triples violate an identity, each sample misses them with
+// probability 1-eps, so n clean samples happen with probability
+// (1-eps)^n <= exp(-eps*n). Turning that around, after n clean samples we
+// can say with confidence 1-delta that fewer than ln(1/delta)/n of the
+// triples are bad. A single bad theta bit in a cocycle on N vectors breaks
+// (A) for about 4N of the N^3 triples, so it takes O(N^2) samples to have a
+// good chance of catching it. Errors in whole rows or in the construction
+// are much denser, and are caught quickly.
+type SampleReport struct {
+	Seed    int64
+	Samples int
+	A       CheckResult
+	Moufang CheckResult
+}
+
+// Pass reports whether no sampled triple failed.
+func (r *SampleReport) Pass() bool {
+	return r.A.Pass && r.Moufang.Pass
+}
+
+// DensityBound returns the fraction of bad triples which could still be
+// present, with confidence 1-delta, given that every sample passed.
+func (r *SampleReport) DensityBound(delta float64) float64 {
+	return math.Log(1/delta) / float64(r.Samples)
+}
+
+// MissProbability returns the chance that the samples all pass even though
+// a fraction density of the triples are bad.
+func (r *SampleReport) MissProbability(density float64) float64 {
+	return math.Pow(1-density, float64(r.Samples))
+}
+
+func (r *SampleReport) String() string {
+	lines := []string{
+		fmt.Sprintf("%d samples, seed 0x%x", r.Samples, r.Seed),
+		r.A.String(),
+		r.Moufang.String(),
+	}
+	if r.Pass() {
+		lines = append(lines, fmt.Sprintf("bad triple density < %.3g with 99%% confidence", r.DensityBound(0.01)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// failsMoufang checks the Moufang identity from [Gri86] p. 226 for the
+// vectors with indices i, j, k.
+func (cl *CL) failsMoufang(i, j, k uint) bool {
+	return cl.thetaByIdxFast(i, j)^cl.thetaByIdxFast(k, i)^cl.thetaByIdxFast(i^j, k^i)^
+		cl.thetaByIdxFast(j, k)^cl.thetaByIdxFast(i, j^k)^cl.thetaByIdxFast(i^j^k, i) != 0
+}
+
+// SampleVerify checks (A) and the Moufang identity on n random triples of
+// vectors. The triples are drawn from a generator seeded with seed, so the
+// same seed always checks the same triples.
+func (cl *CL) SampleVerify(n int, seed int64) *SampleReport {
+	return &SampleReport{
+		Seed:    seed,
+		Samples: n,
+		A:       cl.sampleCheck("(A)", n, seed, cl.failsA),
+		Moufang: cl.sampleCheck("Moufang", n, seed, cl.failsMoufang),
+	}
+}
+
+// sampleCheck runs bad on n random triples of indices.
+func (cl *CL) sampleCheck(name string, n int, seed int64, bad func(i, j, k uint) bool) (res CheckResult) {
+	start := time.Now()
+	res.Name = name
+	rng := rand.New(rand.NewSource(seed))
+	for s := 0; s < n; s++ {
+		i := uint(rng.Int63n(int64(cl.size)))
+		j := uint(rng.Int63n(int64(cl.size)))
+		k := uint(rng.Int63n(int64(cl.size)))
+		if bad(i, j, k) {
+			if res.Violations == 0 {
+				res.Witness = []uint{cl.vs[i], cl.vs[j], cl.vs[k]}
+			}
+			res.Violations++
+		}
+	}
+	res.Pass = res.Violations == 0
+	res.Elapsed = time.Since(start)
+	return
+}
@@ -0,0 +1,37 @@
+package codeloops
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSampleVerifyGolay(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: GolayBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	r := cl.SampleVerify(100000, 1)
+	if !r.Pass() {
+		t.Fatalf("Golay theta failed sampling:\n%s", r)
+	}
+	if b := r.DensityBound(0.01); math.Abs(b-math.Log(100)/100000) > 1e-12 {
+		t.Fatalf("Bad density bound %g", b)
+	}
+
+	// One bad bit breaks (A) for about 4N of the N^3 triples, so 20N^2
+	// samples should find it.
+	cl, err = NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	flipTheta(cl, 5, 9)
+	n := 20 * cl.Size() * cl.Size()
+	r = cl.SampleVerify(n, 1)
+	if r.A.Pass {
+		t.Fatalf("Sampling missed a broken theta:\n%s", r)
+	}
+	r2 := cl.SampleVerify(n, 1)
+	if r2.A.Violations != r.A.Violations || r2.A.Witness[0] != r.A.Witness[0] {
+		t.Fatalf("Same seed gave different results:\n%s\n%s", r, r2)
+	}
+}
@@ -0,0 +1,248 @@
+package codeloops
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc64"
+	"os"
+	"unsafe"
+
+	"github.com/bnagy/codeloops/bitset"
+)
+
+// Theta files hold a precomputed theta table, so that a large loop can be
+// opened without running buildTheta. The layout (all little endian) is
+//
+//	magic      [8]byte "CLTHETA\x00"
+//	version    uint32
+//	basisLen   uint32
+//	flags      uint32  bit 0 set if theta was built with random choices
+//	reserved   uint32
+//	seed       int64
+//	basis      [basisLen]uint64
+//	checksum   uint64  CRC-64 (ECMA) of everything before it, then the
+//	                   theta words
+//	theta      [size*stride]uint64
+//
+// where the theta words are exactly the in-memory rows (see CL.theta), so
+// the table can be mapped and used in place. The header is a multiple of 8
+// bytes, so the words stay aligned. The checksum covers the header as well
+// as theta, so a corrupted basis or seed is caught too.
+
+const (
+	thetaFileMagic   = "CLTHETA\x00"
+	thetaFileVersion = 2
+	thetaFileFixed   = 32 // bytes before the basis
+	// maxStoredBasisLen is the longest basis OpenCL and UnmarshalCL accept.
+	// theta for 24 has 2^48 bits, far more than anyone can store, so
+	// anything longer is corrupt and would only overflow the size sums.
+	maxStoredBasisLen = 24
+)
+
+var crcTable = crc64.MakeTable(crc64.ECMA)
+
+// hostLittleEndian is true when the in-memory word layout matches the file.
+var hostLittleEndian = func() bool {
+	x := uint16(1)
+	return *(*byte)(unsafe.Pointer(&x)) == 1
+}()
+
+// thetaHeaderLen is the offset of the theta words for a basis of length k.
+func thetaHeaderLen(k uint) int {
+	return thetaFileFixed + 8*int(k) + 8
+}
+
+// thetaWords is the number of words in the theta table of a basis of
+// length k, as laid out by initSpace.
+func thetaWords(k uint) uint64 {
+	size := uint64(1) << k
+	return size * ((size + 63) / 64)
+}
+
+// thetaChecksum is the checksum of the header up to the checksum itself,
+// followed by the theta words.
+func thetaChecksum(hdr []byte, words []uint64) uint64 {
+	buf := make([]byte, 8*1024)
+	crc := crc64.Update(0, crcTable, hdr[:len(hdr)-8])
+	for len(words) > 0 {
+		n := len(words)
+		if n > len(buf)/8 {
+			n = len(buf) / 8
+		}
+		for i, w := range words[:n] {
+			binary.LittleEndian.PutUint64(buf[8*i:], w)
+		}
+		crc = crc64.Update(crc, crcTable, buf[:8*n])
+		words = words[n:]
+	}
+	return crc
+}
+
+// WriteThetaFile writes the basis, the construction parameters and the
+// complete theta table to path, for use with OpenCL.
+func (cl *CL) WriteThetaFile(path string) (e error) {
+	if cl.closed {
+		return fmt.Errorf("Can't write a theta file for a closed loop")
+	}
+	if cl.theta == nil {
+		return fmt.Errorf("Can't write a theta file for a loop without theta")
+	}
+	f, e := os.Create(path)
+	if e != nil {
+		return
+	}
+	defer func() {
+		if err := f.Close(); e == nil {
+			e = err
+		}
+	}()
+	w := bufio.NewWriter(f)
+
+	words := cl.theta.Words()
+	var flags uint32
+	if cl.params.Random {
+		flags |= 1
+	}
+	hdr := make([]byte, thetaHeaderLen(cl.basisLen))
+	copy(hdr, thetaFileMagic)
+	binary.LittleEndian.PutUint32(hdr[8:], thetaFileVersion)
+	binary.LittleEndian.PutUint32(hdr[12:], uint32(cl.basisLen))
+	binary.LittleEndian.PutUint32(hdr[16:], flags)
+	binary.LittleEndian.PutUint64(hdr[24:], uint64(cl.params.Seed))
+	for i, b := range cl.basis {
+		binary.LittleEndian.PutUint64(hdr[thetaFileFixed+8*i:], uint64(b))
+	}
+	binary.LittleEndian.PutUint64(hdr[len(hdr)-8:], thetaChecksum(hdr, words))
+	if _, e = w.Write(hdr); e != nil {
+		return
+	}
+	buf := make([]byte, 8)
+	for _, x := range words {
+		binary.LittleEndian.PutUint64(buf, x)
+		if _, e = w.Write(buf); e != nil {
+			return
+		}
+	}
+	return w.Flush()
+}
+
+// OpenCL opens a theta file written by WriteThetaFile. Where the platform
+// supports it the table is memory mapped read-only, so ThetaByIdx and Mul
+// read straight from the page cache and several processes can share one
+// copy. The header and checksum are validated before the loop is returned,
+// but theta isn't checked against the cocycle axioms, so the loop doesn't
+// answer IsAssoc and friends from the basis until VerifyCodeCocycle has
+// passed (see CL.cocycle). Call Close when done with the loop.
+func OpenCL(path string) (cl *CL, e error) {
+	f, e := os.Open(path)
+	if e != nil {
+		return
+	}
+	defer f.Close()
+	fi, e := f.Stat()
+	if e != nil {
+		return
+	}
+
+	fixed := make([]byte, thetaFileFixed)
+	if _, e = f.ReadAt(fixed, 0); e != nil {
+		e = fmt.Errorf("Reading theta file header: %s", e)
+		return
+	}
+	if string(fixed[:8]) != thetaFileMagic {
+		e = fmt.Errorf("%s is not a theta file", path)
+		return
+	}
+	if v := binary.LittleEndian.Uint32(fixed[8:]); v != thetaFileVersion {
+		e = fmt.Errorf("Unsupported theta file version %d", v)
+		return
+	}
+	k := uint(binary.LittleEndian.Uint32(fixed[12:]))
+	if k == 0 || k > maxStoredBasisLen {
+		e = fmt.Errorf("Bad basis length %d in theta file", k)
+		return
+	}
+
+	// The size depends only on k, so check it before allocating anything
+	// for the loop.
+	nWords := thetaWords(k)
+	off := int64(thetaHeaderLen(k))
+	if uint64(fi.Size()) != uint64(off)+8*nWords {
+		e = fmt.Errorf("Theta file is %d bytes, expected %d", fi.Size(), uint64(off)+8*nWords)
+		return
+	}
+
+	cl = new(CL)
+	cl.basisLen = k
+	cl.size = 1 << k
+	cl.params.Random = binary.LittleEndian.Uint32(fixed[16:])&1 != 0
+	cl.params.Seed = int64(binary.LittleEndian.Uint64(fixed[24:]))
+	cl.Seed = fmt.Sprintf("0x%x", cl.params.Seed)
+
+	hdr := make([]byte, thetaHeaderLen(k))
+	if _, e = f.ReadAt(hdr, 0); e != nil {
+		e = fmt.Errorf("Reading theta file header: %s", e)
+		return nil, e
+	}
+	rest := hdr[thetaFileFixed:]
+	cl.basis = make([]uint, k)
+	for i := range cl.basis {
+		cl.basis[i] = uint(binary.LittleEndian.Uint64(rest[8*i:]))
+	}
+	cl.params.Basis = cl.basis
+	sum := binary.LittleEndian.Uint64(rest[len(rest)-8:])
+	if i := dependentVector(cl.basis); i >= 0 {
+		return nil, fmt.Errorf("Basis vector %d (0x%x) in theta file depends on the ones before it", i, cl.basis[i])
+	}
+	cl.initSpace()
+
+	words, unmap, e := mapWords(f, off, int(nWords))
+	if e != nil {
+		return nil, e
+	}
+	if thetaChecksum(hdr, words) != sum {
+		unmap()
+		return nil, fmt.Errorf("Theta file checksum mismatch")
+	}
+	cl.unmap = unmap
+	cl.theta, e = bitset.FromWords(cl.size*cl.thetaStride*64, words)
+	if e != nil {
+		cl.Close()
+		return nil, e
+	}
+	cl.initAlpha()
+	if e = cl.buildAlpha(); e != nil {
+		cl.Close()
+		return nil, e
+	}
+	return
+}
+
+// Close releases the theta table of a loop opened with OpenCL. The loop
+// can't be used afterwards, and anything that needs theta panics. It does
+// nothing for loops built with NewCL.
+func (cl *CL) Close() error {
+	if cl.unmap == nil {
+		return nil
+	}
+	e := cl.unmap()
+	cl.unmap = nil
+	cl.theta = nil
+	cl.closed = true
+	return e
+}
+
+// readWords reads n words at off into memory. This is the fallback when
+// the file can't be mapped in place.
+func readWords(f *os.File, off int64, n int) ([]uint64, error) {
+	buf := make([]byte, 8*n)
+	if _, e := f.ReadAt(buf, off); e != nil {
+		return nil, fmt.Errorf("Reading theta table: %s", e)
+	}
+	words := make([]uint64, n)
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(buf[8*i:])
+	}
+	return words, nil
+}
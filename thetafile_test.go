@@ -0,0 +1,129 @@
+package codeloops
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestThetaFileGolay(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: GolayBasis, Random: true, Seed: 42})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "golay.theta")
+	if err = cl.WriteThetaFile(path); err != nil {
+		t.Fatalf("Failed to write theta file: %s", err)
+	}
+	cl2, err := OpenCL(path)
+	if err != nil {
+		t.Fatalf("Failed to open theta file: %s", err)
+	}
+	defer cl2.Close()
+	if !cl.EqualTheta(cl2) {
+		t.Fatalf("Theta from file doesn't match")
+	}
+	if cl2.Seed != cl.Seed || !cl2.params.Random {
+		t.Fatalf("Params not restored, seed %s", cl2.Seed)
+	}
+	elems := cl.LoopElems()
+	res, res2 := new(CLElem), new(CLElem)
+	for i := 0; i < len(elems); i += 97 {
+		for j := 0; j < len(elems); j += 89 {
+			cl.Mul(&elems[i], &elems[j], res)
+			if _, err = cl2.Mul(&elems[i], &elems[j], res2); err != nil {
+				t.Fatalf("Mul failed: %s", err)
+			}
+			if *res != *res2 {
+				t.Fatalf("Products differ for %v * %v: %v vs %v", elems[i], elems[j], res, res2)
+			}
+		}
+	}
+}
+
+func TestThetaFileCorrupt(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "hamming.theta")
+	if err = cl.WriteThetaFile(path); err != nil {
+		t.Fatalf("Failed to write theta file: %s", err)
+	}
+	data, _ := os.ReadFile(path)
+	data[len(data)-1] ^= 1
+	os.WriteFile(path, data, 0644)
+	if _, err = OpenCL(path); err == nil {
+		t.Fatalf("Corrupt theta file should fail to open")
+	}
+	if err = os.WriteFile(path, data[:20], 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = OpenCL(path); err == nil {
+		t.Fatalf("Truncated theta file should fail to open")
+	}
+}
+
+func TestThetaFileAlphaOnly(t *testing.T) {
+	full, err := NewCL(CLParams{Basis: GolayAwesumBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	cl, err := NewCLFromAlpha(GolayAwesumBasis, full.RestrictedTheta())
+	if err != nil {
+		t.Fatalf("Failed to create CL from alpha: %s", err)
+	}
+	if err = cl.WriteThetaFile(filepath.Join(t.TempDir(), "alpha.theta")); err == nil {
+		t.Fatalf("Writing a loop without theta should fail")
+	}
+}
+
+func TestThetaFileHeader(t *testing.T) {
+	cl, err := NewCL(CLParams{Basis: HammingBasis})
+	if err != nil {
+		t.Fatalf("Failed to create CL: %s", err)
+	}
+	path := filepath.Join(t.TempDir(), "hamming.theta")
+	if err = cl.WriteThetaFile(path); err != nil {
+		t.Fatalf("Failed to write theta file: %s", err)
+	}
+	data, _ := os.ReadFile(path)
+
+	// The seed and the basis are covered by the checksum.
+	for _, off := range []int{24, thetaFileFixed + 8} {
+		bad := append([]byte{}, data...)
+		bad[off] ^= 1
+		os.WriteFile(path, bad, 0644)
+		if _, err = OpenCL(path); err == nil {
+			t.Fatalf("Corrupt header byte %d should fail to open", off)
+		}
+	}
+
+	os.WriteFile(path, data, 0644)
+	cl2, err := OpenCL(path)
+	if err != nil {
+		t.Fatalf("Failed to open theta file: %s", err)
+	}
+	if cl2.cocycle {
+		t.Fatalf("Unverified theta file marked as a code cocycle")
+	}
+	cl2.Close()
+	if err = cl2.WriteThetaFile(path); err == nil {
+		t.Fatalf("Writing a closed loop should fail")
+	}
+
+	// A header claiming a huge basis is rejected on size alone.
+	bad := append([]byte{}, data...)
+	binary.LittleEndian.PutUint32(bad[12:], maxStoredBasisLen)
+	os.WriteFile(path, bad, 0644)
+	if _, err = OpenCL(path); err == nil {
+		t.Fatalf("Theta file with the wrong size for its basis should fail to open")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Using a closed loop should panic")
+		}
+	}()
+	cl2.ThetaByIdx(1, 2)
+}